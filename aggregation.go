@@ -0,0 +1,264 @@
+package dola
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/stream"
+)
+
+// +-------------+
+// | Aggregation |
+// +-------------+
+
+var ErrInvalidAggregation = errors.New("invalid aggregation")
+
+// Aggregation selects how a historian records the values within each interval.
+//
+// Aggregates are recorded once their interval closes: when the first value of a
+// later interval arrives or, failing that, once the interval's time is up.  Time
+// is measured on the events' clock, i.e. an interval opened by a value
+// timestamped a second before its end closes a second later, even if that value
+// arrived late.  Intervals closed by the clock are recorded from a timer
+// goroutine.  Late values count towards the interval that's open, or the next
+// one if none is.
+type Aggregation int
+
+const (
+	// SampleFirst records the first value of each interval as soon as it
+	// arrives and drops the rest.
+	SampleFirst Aggregation = iota
+	// The following record the aggregate of each interval once it closes.
+	AggregateFirst
+	AggregateLast
+	AggregateMean
+	AggregateMin
+	AggregateMax
+	AggregateSum
+	AggregateCount
+	// AggregateOHLC records a Bar.
+	AggregateOHLC
+)
+
+// Bar summarises the values of an interval.
+type Bar struct {
+	Start time.Time
+	Open  float64
+	High  float64
+	Low   float64
+	Close float64
+	// Count is the number of values.
+	Count int
+}
+
+// ExtractBar extracts a Bar out of a stream.KlineData, e.g. to aggregate candles
+// into longer ones.
+func ExtractBar(x interface{}) (Bar, bool) {
+	c, ok := x.(stream.KlineData)
+
+	return Bar{
+		Start: c.StartTime,
+		Open:  c.OpenPrice,
+		High:  c.HighPrice,
+		Low:   c.LowPrice,
+		Close: c.ClosePrice,
+		Count: 1,
+	}, ok
+}
+
+func checkAggregation(c HistorianConfig) error {
+	switch {
+	case c.Aggregation < SampleFirst || c.Aggregation > AggregateOHLC:
+		return fmt.Errorf("%w: %d", ErrInvalidAggregation, c.Aggregation)
+	case c.Aggregation != SampleFirst && c.Interval <= 0:
+		return fmt.Errorf("%w: an interval is required", ErrInvalidAggregation)
+	case c.FillEmpty && c.Aggregation == SampleFirst:
+		return fmt.Errorf("%w: SampleFirst can't fill empty intervals", ErrInvalidAggregation)
+	default:
+		return nil
+	}
+}
+
+// aggregator aggregates values interval by interval.  Aggregates are passed on
+// to onFloat or, for AggregateOHLC, to onBar, along with the end of their
+// interval.  Neither the aggregator nor its callbacks box values, so typed
+// historians stay allocation-free.
+type aggregator struct {
+	mode     Aggregation
+	interval time.Duration
+	fill     bool
+	// limit caps the number of empty intervals filled in a row, as filling
+	// more than the state holds is pointless.
+	limit   int
+	onFloat func(now time.Time, x float64)
+	onBar   func(now time.Time, x Bar)
+
+	// epoch is the open interval, if n > 0.
+	epoch int64
+	n     int
+	sum   float64
+	bar   Bar
+	// closed is the last interval recorded, including filled ones, if any
+	// was.
+	closed    int64
+	hasClosed bool
+	// last is the value recorded for the last interval closed.
+	last float64
+}
+
+func newAggregator(c HistorianConfig, onFloat func(time.Time, float64), onBar func(time.Time, Bar)) *aggregator {
+	return &aggregator{
+		mode:      c.Aggregation,
+		interval:  c.Interval,
+		fill:      c.FillEmpty,
+		limit:     c.StateLength,
+		onFloat:   onFloat,
+		onBar:     onBar,
+		epoch:     0,
+		n:         0,
+		sum:       0,
+		bar:       Bar{}, // nolint: exhaustivestruct
+		closed:    0,
+		hasClosed: false,
+		last:      0,
+	}
+}
+
+// add records x, a float64 or a Bar, and records the intervals that got closed
+// by it.  Values of other types are logged and skipped, except when counting.
+func (a *aggregator) add(now time.Time, x interface{}) {
+	switch x := x.(type) {
+	case float64:
+		a.addFloat(now, x)
+	case Bar:
+		a.addBar(now, x)
+	default:
+		if a.mode != AggregateCount {
+			What(log.Warn().Str("type", fmt.Sprintf("%T", x)), "unable to aggregate value, skipping it")
+
+			return
+		}
+
+		a.addBar(now, Bar{}) // nolint: exhaustivestruct
+	}
+}
+
+func (a *aggregator) addFloat(now time.Time, x float64) {
+	a.addBar(now, Bar{Start: now, Open: x, High: x, Low: x, Close: x, Count: 1})
+}
+
+func (a *aggregator) addBar(now time.Time, b Bar) {
+	epoch := now.UnixNano() / a.interval.Nanoseconds()
+
+	if a.n > 0 && epoch > a.epoch {
+		a.close()
+	}
+
+	if a.n == 0 {
+		if a.hasClosed && epoch <= a.closed {
+			epoch = a.closed + 1
+		}
+
+		a.fillUpTo(epoch)
+		a.epoch = epoch
+		a.bar = b
+		a.bar.Start = a.start(a.epoch)
+	} else {
+		a.bar.High = math.Max(a.bar.High, b.High)
+		a.bar.Low = math.Min(a.bar.Low, b.Low)
+		a.bar.Close = b.Close
+	}
+
+	a.n++
+	a.bar.Count = a.n
+	a.sum += b.Close
+}
+
+// flush closes the open interval, if any, as its time is up.
+func (a *aggregator) flush() {
+	if a.n > 0 {
+		a.close()
+	}
+}
+
+// end returns when the open interval ends.
+func (a *aggregator) end() time.Time {
+	return a.start(a.epoch + 1)
+}
+
+// close records the value of the open interval.
+func (a *aggregator) close() {
+	a.last = a.value()
+	a.emit(a.epoch, a.last, a.bar)
+	a.closed, a.hasClosed = a.epoch, true
+	a.n = 0
+	a.sum = 0
+}
+
+// fillUpTo records the empty intervals since the last one closed up to next, if
+// filling.
+func (a *aggregator) fillUpTo(next int64) {
+	if !a.fill || !a.hasClosed {
+		return
+	}
+
+	for epoch := a.closed + 1; epoch < next && epoch <= a.closed+int64(a.limit); epoch++ {
+		c := a.bar.Close
+		a.emit(epoch, a.filler(), Bar{Start: a.start(epoch), Open: c, High: c, Low: c, Close: c, Count: 0})
+	}
+
+	if next-1 > a.closed {
+		a.closed = next - 1
+	}
+}
+
+func (a *aggregator) emit(epoch int64, x float64, b Bar) {
+	if a.mode == AggregateOHLC {
+		a.onBar(a.start(epoch+1), b)
+	} else {
+		a.onFloat(a.start(epoch+1), x)
+	}
+}
+
+// value returns the float64 aggregate of the open interval.  Bars are kept in
+// a.bar.
+func (a *aggregator) value() float64 {
+	switch a.mode {
+	case AggregateFirst:
+		return a.bar.Open
+	case AggregateLast:
+		return a.bar.Close
+	case AggregateMean:
+		return a.sum / float64(a.n)
+	case AggregateMin:
+		return a.bar.Low
+	case AggregateMax:
+		return a.bar.High
+	case AggregateSum:
+		return a.sum
+	case AggregateCount:
+		return float64(a.n)
+	case AggregateOHLC:
+		return a.bar.Close
+	case SampleFirst:
+	}
+
+	panic("unreachable")
+}
+
+// filler returns the float64 recorded for empty intervals.
+func (a *aggregator) filler() float64 {
+	switch a.mode { // nolint: exhaustive
+	case AggregateSum, AggregateCount:
+		return 0
+	default:
+		return a.last
+	}
+}
+
+func (a *aggregator) start(epoch int64) time.Time {
+	return time.Unix(0, epoch*a.interval.Nanoseconds()).UTC()
+}
@@ -0,0 +1,260 @@
+package dola_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/numeusxyz/dola"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/ticker"
+)
+
+// nolint: exhaustivestruct, funlen
+func TestAggregation(t *testing.T) {
+	t.Parallel()
+
+	t0 := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	feed := func(h *dola.HistoryStrategy) {
+		var (
+			k dola.Keep
+			e = newFakeExchange("fake")
+		)
+
+		for _, x := range []struct {
+			d    time.Duration
+			last float64
+		}{{10 * time.Second, 1}, {20 * time.Second, 3}, {70 * time.Second, 2}, {200 * time.Second, 5}} {
+			_ = h.OnPrice(&k, e, ticker.Price{Last: x.last, LastUpdated: t0.Add(x.d)})
+		}
+	}
+
+	// Intervals 0 and 1 are closed, 2 is empty and 3 is still open.
+	cases := []struct {
+		mode       dola.Aggregation
+		want, fill []float64
+	}{
+		{dola.AggregateFirst, []float64{1, 2}, []float64{1, 2, 2}},
+		{dola.AggregateLast, []float64{3, 2}, []float64{3, 2, 2}},
+		{dola.AggregateMean, []float64{2, 2}, []float64{2, 2, 2}},
+		{dola.AggregateMin, []float64{1, 2}, []float64{1, 2, 2}},
+		{dola.AggregateMax, []float64{3, 2}, []float64{3, 2, 2}},
+		{dola.AggregateSum, []float64{4, 2}, []float64{4, 2, 0}},
+		{dola.AggregateCount, []float64{2, 1}, []float64{2, 1, 0}},
+	}
+
+	for _, c := range cases {
+		for _, fill := range []bool{false, true} {
+			var (
+				h     = dola.NewHistoryStrategy()
+				state []float64
+			)
+
+			_, err := h.AddHistorian(dola.HistorianConfig{
+				Exchange:    "fake",
+				Event:       dola.OnPriceEvent,
+				Interval:    time.Minute,
+				StateLength: 10,
+				Aggregation: c.mode,
+				FillEmpty:   fill,
+				Extract:     dola.Boxed(dola.ExtractLast),
+				F:           func(x dola.Array) { state = x.Floats() },
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			feed(&h)
+
+			want := c.want
+			if fill {
+				want = c.fill
+			}
+
+			if diff := cmp.Diff(want, state); diff != "" {
+				t.Errorf("mode %d, fill %v: %s", c.mode, fill, diff)
+			}
+		}
+	}
+
+	// Bars.
+	var (
+		h    = dola.NewHistoryStrategy()
+		bars []dola.Bar
+	)
+
+	_, err := dola.AddTypedHistorian(&h, dola.TypedHistorianConfig[dola.Bar]{
+		HistorianConfig: dola.HistorianConfig{
+			Exchange:    "fake",
+			Event:       dola.OnPriceEvent,
+			Interval:    time.Minute,
+			StateLength: 10,
+			Aggregation: dola.AggregateOHLC,
+			FillEmpty:   true,
+		},
+		Extract: func(x interface{}) (dola.Bar, bool) {
+			p := x.(ticker.Price)
+
+			return dola.Bar{Open: p.Last, High: p.Last, Low: p.Last, Close: p.Last, Count: 1}, true
+		},
+		F: func(x *dola.Ring[dola.Bar]) { bars = x.AppendTo(bars[:0]) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feed(&h)
+
+	want := []dola.Bar{
+		{Start: t0, Open: 1, High: 3, Low: 1, Close: 3, Count: 2},
+		{Start: t0.Add(time.Minute), Open: 2, High: 2, Low: 2, Close: 2, Count: 1},
+		{Start: t0.Add(2 * time.Minute), Open: 2, High: 2, Low: 2, Close: 2, Count: 0},
+	}
+	if diff := cmp.Diff(want, bars); diff != "" {
+		t.Error(diff)
+	}
+}
+
+// nolint: exhaustivestruct
+func TestAggregation_Invalid(t *testing.T) {
+	t.Parallel()
+
+	h := dola.NewHistoryStrategy()
+
+	for _, c := range []dola.HistorianConfig{
+		{Exchange: "fake", Event: dola.OnPriceEvent, Aggregation: dola.AggregateMean},
+		// Tickers can't be averaged.
+		{Exchange: "fake", Event: dola.OnPriceEvent, Interval: time.Minute, Aggregation: dola.AggregateMean},
+		{Exchange: "fake", Event: dola.OnPriceEvent, Interval: time.Minute, FillEmpty: true},
+		{Exchange: "fake", Event: dola.OnPriceEvent, Interval: time.Minute, Aggregation: 42},
+	} {
+		if _, err := h.AddHistorian(c); !errors.Is(err, dola.ErrInvalidAggregation) {
+			t.Errorf("have %v, want ErrInvalidAggregation", err)
+		}
+	}
+
+	_, err := dola.AddTypedHistorian(&h, dola.TypedHistorianConfig[float64]{
+		HistorianConfig: dola.HistorianConfig{
+			Exchange:    "fake",
+			Event:       dola.OnPriceEvent,
+			Interval:    time.Minute,
+			Aggregation: dola.AggregateOHLC,
+		},
+	})
+	if !errors.Is(err, dola.ErrInvalidAggregation) {
+		t.Errorf("have %v, want ErrInvalidAggregation", err)
+	}
+}
+
+// nolint: exhaustivestruct
+func TestAggregation_SkipsInvalidValues(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k dola.Keep
+		e = newFakeExchange("fake")
+		h = dola.NewHistoryStrategy()
+	)
+
+	handle, err := h.AddHistorian(dola.HistorianConfig{
+		Exchange:    "fake",
+		Event:       dola.OnPriceEvent,
+		Interval:    time.Minute,
+		StateLength: 10,
+		Aggregation: dola.AggregateMean,
+		// Extracts tickers rather than float64s.
+		Extract: func(x interface{}) (interface{}, bool) { return x, true },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		_ = h.OnPrice(&k, e, ticker.Price{Last: 1, LastUpdated: time.Unix(int64(60*i), 0)})
+	}
+
+	if n := handle.Snapshot().Len(); n != 0 {
+		t.Errorf("have %d values, want none", n)
+	}
+}
+
+// nolint: exhaustivestruct
+func TestAggregation_ClosesOnTime(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k     dola.Keep
+		e     = newFakeExchange("fake")
+		h     = dola.NewHistoryStrategy()
+		mu    sync.Mutex
+		state []float64
+	)
+
+	_, err := h.AddHistorian(dola.HistorianConfig{
+		Exchange:    "fake",
+		Event:       dola.OnPriceEvent,
+		Interval:    20 * time.Millisecond,
+		StateLength: 10,
+		Aggregation: dola.AggregateSum,
+		Extract:     dola.Boxed(dola.ExtractLast),
+		F: func(x dola.Array) {
+			mu.Lock()
+			state = x.Floats()
+			mu.Unlock()
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// No value of a later interval ever arrives.
+	now := time.Now()
+	_ = h.OnPrice(&k, e, ticker.Price{Last: 1, LastUpdated: now})
+	_ = h.OnPrice(&k, e, ticker.Price{Last: 2, LastUpdated: now})
+
+	waitFor(func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return len(state) > 0
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if diff := cmp.Diff([]float64{3}, state); diff != "" {
+		t.Error(diff)
+	}
+}
+
+// nolint: exhaustivestruct, paralleltest // AllocsPerRun doesn't support parallel tests.
+func TestAggregation_Allocations(t *testing.T) {
+	var (
+		k dola.Keep
+		e = newFakeExchange("fake")
+		h = dola.NewHistoryStrategy()
+		p = ticker.Price{Last: 1, LastUpdated: time.Now()}
+	)
+
+	// Boxing the event itself is the only allocation.
+	base := testing.AllocsPerRun(100, func() { _ = h.OnPrice(&k, e, p) })
+
+	_, err := dola.AddTypedHistorian(&h, dola.TypedHistorianConfig[float64]{
+		HistorianConfig: dola.HistorianConfig{
+			Exchange:    "fake",
+			Event:       dola.OnPriceEvent,
+			Interval:    time.Hour,
+			StateLength: 16,
+			Aggregation: dola.AggregateMean,
+		},
+		Extract: dola.ExtractLast,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if allocs := testing.AllocsPerRun(100, func() { _ = h.OnPrice(&k, e, p) }); allocs != base {
+		t.Errorf("have %v allocations, want %v", allocs, base)
+	}
+}
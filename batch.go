@@ -0,0 +1,189 @@
+package dola
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+	"go.uber.org/multierr"
+)
+
+// +--------------+
+// | Batch orders |
+// +--------------+
+
+var (
+	ErrRolledBack        = errors.New("order cancelled as part of an all-or-nothing rollback")
+	ErrBatchNotSubmitted = errors.New("order not placed by batch submission")
+)
+
+// BatchSubmitter can be implemented by exchange wrappers with a native batch
+// order endpoint.  It returns a response for each order, in the same order;
+// orders that weren't placed have IsOrderPlaced unset.  GCT's exchanges don't
+// implement it, as GCT has no common batch order API, so their orders are
+// submitted concurrently instead.
+type BatchSubmitter interface {
+	SubmitBatchOrders(ctx context.Context, xs []order.Submit) ([]order.SubmitResponse, error)
+}
+
+type BatchOptions struct {
+	// Concurrency limits the number of orders submitted at the same time.  Zero
+	// means no limit.
+	Concurrency int
+	// AllOrNothing cancels all submitted orders if any submission fails.
+	AllOrNothing bool
+}
+
+// SubmitResult is the outcome of submitting a single order of a batch.
+type SubmitResult struct {
+	Submit   order.Submit
+	Response order.SubmitResponse
+	Err      error
+	// RolledBack is set if the order was placed, but then cancelled as
+	// another one failed.
+	RolledBack bool
+}
+
+func (bot *Keep) submitConcurrently(ctx context.Context, e exchange.IBotExchange, limit int, results []SubmitResult) {
+	var (
+		wg  sync.WaitGroup
+		sem chan struct{}
+	)
+
+	if limit > 0 {
+		sem = make(chan struct{}, limit)
+	}
+
+	for i := range results {
+		wg.Add(1)
+
+		if sem != nil {
+			sem <- struct{}{}
+		}
+
+		go func(r *SubmitResult) {
+			defer wg.Done()
+
+			r.Response, r.Err = bot.SubmitOrder(ctx, e, r.Submit)
+
+			if sem != nil {
+				<-sem
+			}
+		}(&results[i])
+	}
+
+	wg.Wait()
+}
+
+// submitBatch sends all orders passing the pre-submit checks in a single
+// request.
+func (bot *Keep) submitBatch(ctx context.Context, e exchange.IBotExchange, b BatchSubmitter, results []SubmitResult) {
+	var (
+		xs       []order.Submit
+		indices  []int
+		releases []func()
+	)
+
+	defer func() {
+		for _, release := range releases {
+			release()
+		}
+	}()
+
+	for i := range results {
+		release, err := bot.preSubmit(e, &results[i].Submit)
+		if err != nil {
+			results[i].Err = err
+
+			continue
+		}
+
+		releases = append(releases, release)
+
+		xs = append(xs, results[i].Submit)
+		indices = append(indices, i)
+	}
+
+	if len(xs) == 0 {
+		return
+	}
+
+	if err := bot.throttleTrading(ctx, e, SubmitRequest); err != nil {
+		for _, i := range indices {
+			results[i].Err = err
+		}
+
+		return
+	}
+
+	bot.ReportEvent(SubmitOrderMetric, e.GetName())
+
+	defer bot.ReportLatency(SubmitOrderLatencyMetric, time.Now(), e.GetName())
+
+	resps, err := b.SubmitBatchOrders(ctx, xs)
+
+	for j, i := range indices {
+		r := &results[i]
+
+		switch {
+		case err != nil:
+			r.Err = err
+		case j >= len(resps) || !resps[j].IsOrderPlaced:
+			r.Err = ErrBatchNotSubmitted
+		default:
+			r.Response = resps[j]
+			r.Err = bot.postSubmit(ctx, e, bot.entry(e), r.Submit, r.Response, nil)
+		}
+
+		if r.Err != nil {
+			bot.ReportEvent(SubmitOrderErrorMetric, e.GetName())
+		}
+	}
+}
+
+// rollback cancels all orders of a batch that were placed, including those that
+// failed afterwards, e.g. with ErrOrdersAlreadyExists.
+func (bot *Keep) rollback(ctx context.Context, e exchange.IBotExchange, results []SubmitResult) error {
+	var multi error
+
+	for i := range results {
+		r := &results[i]
+
+		if r.Response.OrderID == "" || (r.Err != nil && !r.Response.IsOrderPlaced) {
+			continue
+		}
+
+		// nolint: exhaustivestruct
+		err := bot.CancelOrder(ctx, e, order.Cancel{
+			ID:            r.Response.OrderID,
+			ClientOrderID: r.Submit.ClientOrderID,
+			Side:          r.Submit.Side,
+			AssetType:     r.Submit.AssetType,
+			Pair:          r.Submit.Pair,
+		})
+		if err != nil {
+			multi = multierr.Append(multi, fmt.Errorf("unable to roll back order %s: %w", r.Response.OrderID, err))
+
+			continue
+		}
+
+		r.Err = multierr.Append(r.Err, ErrRolledBack)
+		r.RolledBack = true
+	}
+
+	return multi
+}
+
+func combineResults(results []SubmitResult) error {
+	var multi error
+
+	for _, r := range results {
+		multi = multierr.Append(multi, r.Err)
+	}
+
+	return multi
+}
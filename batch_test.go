@@ -0,0 +1,186 @@
+package dola_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/numeusxyz/dola"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+)
+
+var errTooLarge = errors.New("too large")
+
+// batchExchange is a fakeExchange with a native batch order endpoint.
+type batchExchange struct {
+	*fakeExchange
+
+	batches int
+}
+
+func (b *batchExchange) SubmitBatchOrders(ctx context.Context, xs []order.Submit) ([]order.SubmitResponse, error) {
+	b.batches++
+
+	resps := make([]order.SubmitResponse, len(xs))
+	for i := range xs {
+		resps[i], _ = b.SubmitOrder(ctx, &xs[i])
+	}
+
+	return resps, nil
+}
+
+// nolint: exhaustivestruct
+func batchOrders(amounts ...float64) []order.Submit {
+	xs := make([]order.Submit, 0, len(amounts))
+
+	for _, amount := range amounts {
+		xs = append(xs, order.Submit{
+			Amount:    amount,
+			Price:     100,
+			Type:      order.Limit,
+			Side:      order.Buy,
+			Pair:      currency.NewPair(currency.BTC, currency.USDT),
+			AssetType: asset.Spot,
+		})
+	}
+
+	return xs
+}
+
+func rejectLarge(x order.Submit) error {
+	if x.Amount > 10 {
+		return errTooLarge
+	}
+
+	return nil
+}
+
+// nolint: exhaustivestruct
+func TestKeep_SubmitOrders(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k dola.Keep
+		e = newFakeExchange("fake")
+	)
+
+	e.reject = rejectLarge
+
+	results, err := k.SubmitOrders(context.Background(), e, dola.BatchOptions{Concurrency: 2},
+		batchOrders(1, 20, 3)...)
+	if !errors.Is(err, errTooLarge) {
+		t.Errorf("have %v, want %v", err, errTooLarge)
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("have %d results, want 3", len(results))
+	}
+
+	for i, want := range []float64{1, 20, 3} {
+		if results[i].Submit.Amount != want {
+			t.Errorf("result %d: have amount %v, want %v", i, results[i].Submit.Amount, want)
+		}
+	}
+
+	if results[0].Err != nil || results[0].Response.OrderID == "" || !errors.Is(results[1].Err, errTooLarge) {
+		t.Errorf("unexpected results: %+v", results)
+	}
+
+	if len(e.cancellations()) != 0 {
+		t.Error("orders got rolled back without AllOrNothing")
+	}
+}
+
+// nolint: exhaustivestruct
+func TestKeep_SubmitOrders_AllOrNothing(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k dola.Keep
+		e = &batchExchange{fakeExchange: newFakeExchange("fake")}
+	)
+
+	e.reject = rejectLarge
+
+	results, err := k.SubmitOrders(context.Background(), e, dola.BatchOptions{AllOrNothing: true},
+		batchOrders(1, 20, 3)...)
+	if !errors.Is(err, dola.ErrBatchNotSubmitted) {
+		t.Errorf("have %v, want %v", err, dola.ErrBatchNotSubmitted)
+	}
+
+	if e.batches != 1 {
+		t.Errorf("have %d batch requests, want 1", e.batches)
+	}
+
+	for _, i := range []int{0, 2} {
+		if !results[i].RolledBack || !errors.Is(results[i].Err, dola.ErrRolledBack) {
+			t.Errorf("result %d not rolled back: %+v", i, results[i])
+		}
+	}
+
+	if xs := e.cancellations(); len(xs) != 2 || xs[0].ID != "1" || xs[1].ID != "2" {
+		t.Errorf("unexpected cancellations: %+v", xs)
+	}
+}
+
+// nolint: exhaustivestruct
+func TestKeep_SubmitOrders_MaxOpenOrders(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k dola.Keep
+		e = &batchExchange{fakeExchange: newFakeExchange("fake")}
+	)
+
+	k.SetRiskChecks(dola.MaxOpenOrdersCheck{Limit: 2})
+
+	// None of the orders is stored before the whole batch is sent.
+	results, err := k.SubmitOrders(context.Background(), e, dola.BatchOptions{}, batchOrders(1, 2, 3, 4)...)
+	if !errors.Is(err, dola.ErrMaxOpenOrders) {
+		t.Errorf("have %v, want %v", err, dola.ErrMaxOpenOrders)
+	}
+
+	for i, r := range results {
+		if (i < 2) != (r.Err == nil) {
+			t.Errorf("unexpected result %d: %v", i, r.Err)
+		}
+	}
+
+	if n := len(e.submissions()); n != 2 {
+		t.Errorf("have %d submissions, want 2", n)
+	}
+}
+
+// nolint: exhaustivestruct
+func TestKeep_SubmitOrders_RollbackPlacedFailures(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k dola.Keep
+		e = &batchExchange{fakeExchange: newFakeExchange("fake")}
+		x = batchOrders(1)[0]
+	)
+
+	// The second order gets placed, but its ID is taken in the registry.
+	if _, err := k.SubmitConditional(e, dola.ConditionalOrder{ID: "2", Trigger: 1, Submit: x}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	e.reject = rejectLarge
+
+	results, err := k.SubmitOrders(context.Background(), e, dola.BatchOptions{AllOrNothing: true},
+		batchOrders(1, 3, 20)...)
+	if !errors.Is(err, dola.ErrOrdersAlreadyExists) {
+		t.Errorf("have %v, want %v", err, dola.ErrOrdersAlreadyExists)
+	}
+
+	if !results[1].RolledBack || !errors.Is(results[1].Err, dola.ErrRolledBack) {
+		t.Errorf("result 1 not rolled back: %+v", results[1])
+	}
+
+	if xs := e.cancellations(); len(xs) != 2 || xs[0].ID != "1" || xs[1].ID != "2" {
+		t.Errorf("unexpected cancellations: %+v", xs)
+	}
+}
@@ -2,37 +2,39 @@ package dola
 
 import "fmt"
 
-// +---------------+
-// | CircularArray |
-// +---------------+
+// +------+
+// | Ring |
+// +------+
 
-type CircularArray struct {
+// Ring is a fixed-capacity circular buffer.  Once full, each Push overwrites the
+// oldest element.  Pushing never allocates.
+type Ring[T any] struct {
 	Offset int
-	xs     []interface{}
+	xs     []T
 }
 
-func NewCircularArray(n int) CircularArray {
-	xs := make([]interface{}, 0, n)
+func NewRing[T any](n int) Ring[T] {
+	xs := make([]T, 0, n)
 	if cap(xs) != n {
 		panic("")
 	}
 
-	return CircularArray{
+	return Ring[T]{
 		Offset: 0,
 		xs:     xs,
 	}
 }
 
 // Index maps an external 0-based index to the corresponding internal index.
-func (a *CircularArray) Index(i int) int {
+func (a *Ring[T]) Index(i int) int {
 	return (i + a.Offset) % cap(a.xs)
 }
 
-func (a *CircularArray) LastIndex() int {
+func (a *Ring[T]) LastIndex() int {
 	return a.Index(len(a.xs) - 1)
 }
 
-func (a *CircularArray) Push(x interface{}) {
+func (a *Ring[T]) Push(x T) {
 	if len(a.xs) < cap(a.xs) {
 		a.xs = append(a.xs, x)
 	} else {
@@ -42,24 +44,65 @@ func (a *CircularArray) Push(x interface{}) {
 	}
 }
 
-// +-----------------+
-// | Array interface |
-// +-----------------+
-
-func (a *CircularArray) Len() int {
+func (a *Ring[T]) Len() int {
 	return len(a.xs)
 }
 
-func (a *CircularArray) At(index int) interface{} {
+func (a *Ring[T]) Cap() int {
+	return cap(a.xs)
+}
+
+func (a *Ring[T]) At(index int) T {
 	mapped := a.Index(index)
 
 	return a.xs[mapped]
 }
 
-func (a *CircularArray) Last() interface{} {
+func (a *Ring[T]) Last() T {
 	return a.At(a.Len() - 1)
 }
 
+// Range calls f for each element, oldest first.  If f returns false, Range stops
+// the iteration.
+func (a *Ring[T]) Range(f func(i int, x T) bool) {
+	for i := 0; i < len(a.xs); i++ {
+		if !f(i, a.xs[a.Index(i)]) {
+			return
+		}
+	}
+}
+
+// AppendTo appends the elements, oldest first, to dst and returns the extended
+// slice.  Reusing dst between calls avoids allocations.
+func (a *Ring[T]) AppendTo(dst []T) []T {
+	if len(a.xs) < cap(a.xs) {
+		return append(dst, a.xs...)
+	}
+
+	dst = append(dst, a.xs[a.Offset:]...)
+
+	return append(dst, a.xs[:a.Offset]...)
+}
+
+// +---------------+
+// | CircularArray |
+// +---------------+
+
+// CircularArray is a Ring of arbitrary values implementing Array.
+type CircularArray struct {
+	Ring[interface{}]
+}
+
+func NewCircularArray(n int) CircularArray {
+	return CircularArray{
+		Ring: NewRing[interface{}](n),
+	}
+}
+
+// +-----------------+
+// | Array interface |
+// +-----------------+
+
 func (a *CircularArray) Floats() []float64 {
 	ys := make([]float64, a.Len())
 
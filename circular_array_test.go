@@ -3,6 +3,7 @@ package dola_test
 import (
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/numeusxyz/dola"
 )
 
@@ -136,3 +137,55 @@ func TestCircularArray_Index(t *testing.T) {
 	f(1, 0)
 	f(2, 1)
 }
+
+func TestRing(t *testing.T) {
+	t.Parallel()
+
+	r := dola.NewRing[float64](3)
+	for _, x := range []float64{1, 2, 3, 4} {
+		r.Push(x)
+	}
+
+	if r.Len() != 3 || r.Cap() != 3 || r.At(0) != 2 || r.Last() != 4 {
+		t.Errorf("have len=%d, cap=%d, first=%v, last=%v", r.Len(), r.Cap(), r.At(0), r.Last())
+	}
+
+	var xs []float64
+
+	r.Range(func(i int, x float64) bool {
+		xs = append(xs, x)
+
+		return i < 1
+	})
+
+	if diff := cmp.Diff([]float64{2, 3}, xs); diff != "" {
+		t.Error(diff)
+	}
+
+	if diff := cmp.Diff([]float64{0, 2, 3, 4}, r.AppendTo([]float64{0})); diff != "" {
+		t.Error(diff)
+	}
+}
+
+// nolint: paralleltest // AllocsPerRun doesn't support parallel tests.
+func TestRing_Allocations(t *testing.T) {
+	var (
+		r   = dola.NewRing[float64](16)
+		buf = make([]float64, 0, 16)
+		sum float64
+	)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		r.Push(1)
+		r.Range(func(i int, x float64) bool {
+			sum += x
+
+			return true
+		})
+		buf = r.AppendTo(buf[:0])
+	})
+
+	if allocs != 0 {
+		t.Errorf("have %v allocations, want none", allocs)
+	}
+}
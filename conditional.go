@@ -0,0 +1,473 @@
+package dola
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/orderbook"
+	"go.uber.org/multierr"
+)
+
+// +------------------+
+// | ConditionalOrder |
+// +------------------+
+
+var ErrConditionalNotFound = errors.New("conditional order not found")
+
+type TriggerDirection int
+
+const (
+	// TriggerAtOrAbove fires once the price rises to Trigger or above.
+	TriggerAtOrAbove TriggerDirection = iota
+	// TriggerAtOrBelow fires once the price falls to Trigger or below.
+	TriggerAtOrBelow
+)
+
+// ConditionalOrder is kept by Keep, client-side, until the price of its pair
+// crosses Trigger.  Submit is then sent to the exchange as a regular market or
+// limit order.
+type ConditionalOrder struct {
+	ID        string
+	Exchange  string
+	Trigger   float64
+	Direction TriggerDirection
+	Submit    order.Submit
+	Created   time.Time
+}
+
+// StopLoss returns a conditional order that submits x once the price moves
+// against it, i.e. falls to trigger for sells and rises to trigger for buys.
+func StopLoss(x order.Submit, trigger float64) ConditionalOrder {
+	c := newConditionalOrder(x, trigger)
+	if isBuy(x.Side) {
+		c.Direction = TriggerAtOrAbove
+	} else {
+		c.Direction = TriggerAtOrBelow
+	}
+
+	return c
+}
+
+// TakeProfit returns a conditional order that submits x once the price moves in
+// its favour, i.e. rises to trigger for sells and falls to trigger for buys.
+func TakeProfit(x order.Submit, trigger float64) ConditionalOrder {
+	c := newConditionalOrder(x, trigger)
+	if isBuy(x.Side) {
+		c.Direction = TriggerAtOrBelow
+	} else {
+		c.Direction = TriggerAtOrAbove
+	}
+
+	return c
+}
+
+func newConditionalOrder(x order.Submit, trigger float64) ConditionalOrder {
+	return ConditionalOrder{
+		ID:        "",
+		Exchange:  x.Exchange,
+		Trigger:   trigger,
+		Direction: TriggerAtOrAbove,
+		Submit:    x,
+		Created:   time.Time{},
+	}
+}
+
+func isBuy(side order.Side) bool {
+	return side == order.Buy || side == order.Bid
+}
+
+// Triggered reports whether price crosses the order's trigger.
+func (c ConditionalOrder) Triggered(price float64) bool {
+	if price <= 0 {
+		return false
+	}
+
+	if c.Direction == TriggerAtOrAbove {
+		return price >= c.Trigger
+	}
+
+	return price <= c.Trigger
+}
+
+// Detail returns how the conditional order is shown in the order registry.
+func (c ConditionalOrder) Detail(status order.Status) order.Detail {
+	typ := order.Stop
+	if c.Submit.Type == order.Limit {
+		typ = order.StopLimit
+	}
+
+	// nolint: exhaustivestruct
+	return order.Detail{
+		Price:           c.Submit.Price,
+		Amount:          c.Submit.Amount,
+		TriggerPrice:    c.Trigger,
+		RemainingAmount: c.Submit.Amount,
+		Exchange:        c.Exchange,
+		ID:              c.ID,
+		ClientOrderID:   c.Submit.ClientOrderID,
+		Type:            typ,
+		Side:            c.Submit.Side,
+		Status:          status,
+		AssetType:       c.Submit.AssetType,
+		Date:            c.Created,
+		LastUpdated:     time.Now(),
+		Pair:            c.Submit.Pair,
+	}
+}
+
+// +-----------------+
+// | conditionalBook |
+// +-----------------+
+
+// conditionalBook holds pending conditional orders and, if path is set,
+// persists them as JSON after every change.  UserData is not persisted.
+// Triggered orders stay in the book until their submission succeeds or fails
+// permanently.
+type conditionalBook struct {
+	mu       sync.Mutex
+	path     string
+	orders   map[string]ConditionalOrder
+	userData map[string]interface{}
+	// triggering holds the IDs of the orders being submitted.
+	triggering map[string]bool
+}
+
+// conditionalRecord is the persisted form of a ConditionalOrder.  The pair is
+// stored explicitly as GCT can't always parse its own pair strings back.
+type conditionalRecord struct {
+	ConditionalOrder
+	Base      string
+	Quote     string
+	Delimiter string
+}
+
+func (b *conditionalBook) init() {
+	if b.orders == nil {
+		b.orders = make(map[string]ConditionalOrder)
+		b.userData = make(map[string]interface{})
+		b.triggering = make(map[string]bool)
+	}
+}
+
+// load reads the persisted conditional orders, if any.
+func (b *conditionalBook) load() ([]ConditionalOrder, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.init()
+
+	if b.path == "" || !FileExists(b.path) {
+		return nil, nil
+	}
+
+	data, err := ioutil.ReadFile(b.path)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []conditionalRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, err
+	}
+
+	xs := make([]ConditionalOrder, 0, len(records))
+
+	for _, r := range records {
+		c := r.ConditionalOrder
+		c.Submit.Pair = currency.NewPairWithDelimiter(r.Base, r.Quote, r.Delimiter)
+		b.orders[c.ID] = c
+		xs = append(xs, c)
+	}
+
+	return xs, nil
+}
+
+// save must be called with b.mu held.
+func (b *conditionalBook) save() error {
+	if b.path == "" {
+		return nil
+	}
+
+	records := make([]conditionalRecord, 0, len(b.orders))
+
+	for _, c := range b.orders {
+		p := c.Submit.Pair
+		c.Submit.Pair = p.Format(currency.DashDelimiter, true)
+
+		records = append(records, conditionalRecord{
+			ConditionalOrder: c,
+			Base:             p.Base.String(),
+			Quote:            p.Quote.String(),
+			Delimiter:        p.Delimiter,
+		})
+	}
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so that a crash never leaves a
+	// truncated file behind.
+	tmp := b.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0o600); err != nil { // nolint: gomnd
+		return err
+	}
+
+	return os.Rename(tmp, b.path)
+}
+
+func (b *conditionalBook) add(c ConditionalOrder, userData interface{}) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.init()
+	b.orders[c.ID] = c
+	b.userData[c.ID] = userData
+
+	return b.save()
+}
+
+// remove deletes the conditional order with the given ID and returns it along
+// with its user data.  triggering is set if the order was being submitted.
+func (b *conditionalBook) remove(id string) (c ConditionalOrder, userData interface{}, triggering bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.orders[id]
+	if !ok {
+		return c, nil, false, fmt.Errorf("%w: %s", ErrConditionalNotFound, id)
+	}
+
+	userData, triggering = b.userData[id], b.triggering[id]
+
+	delete(b.orders, id)
+	delete(b.userData, id)
+	delete(b.triggering, id)
+
+	return c, userData, triggering, b.save()
+}
+
+// rearm makes a triggered order pending again, e.g. after its submission failed
+// transiently, and reports whether it's still in the book.
+func (b *conditionalBook) rearm(id string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.triggering, id)
+	_, ok := b.orders[id]
+
+	return ok
+}
+
+// triggered returns all pending conditional orders for the given market that
+// are triggered by price(side), and marks them as being submitted.
+func (b *conditionalBook) triggered(exchangeName string,
+	a asset.Item,
+	p currency.Pair,
+	price func(side order.Side) float64) ([]ConditionalOrder, []interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var (
+		xs []ConditionalOrder
+		ys []interface{}
+	)
+
+	for id, c := range b.orders {
+		if c.Exchange != exchangeName || c.Submit.AssetType != a || !c.Submit.Pair.Equal(p) {
+			continue
+		}
+
+		if !b.triggering[id] && c.Triggered(price(c.Submit.Side)) {
+			xs = append(xs, c)
+			ys = append(ys, b.userData[id])
+
+			b.triggering[id] = true
+		}
+	}
+
+	return xs, ys
+}
+
+func (b *conditionalBook) list() []ConditionalOrder {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	xs := make([]ConditionalOrder, 0, len(b.orders))
+	for _, c := range b.orders {
+		xs = append(xs, c)
+	}
+
+	return xs
+}
+
+// +--------------------------+
+// | Keep: Conditional orders |
+// +--------------------------+
+
+// SubmitConditional registers a conditional order and returns its ID.  The order
+// shows up in the registry as a synthetic order, with userData attached, until
+// it gets triggered or cancelled.  Its updates are delivered from the exchange's
+// event loop (see Loop).  Once triggered, c.Submit is submitted through
+// SubmitOrderUD with the same userData.
+func (bot *Keep) SubmitConditional(exchangeOrName interface{}, c ConditionalOrder, userData interface{}) (string, error) {
+	e := bot.getExchange(exchangeOrName)
+
+	if err := bot.checkHalted(); err != nil {
+		return "", err
+	}
+
+	if err := c.Submit.Validate(); err != nil {
+		return "", err
+	}
+
+	if c.ID == "" {
+		c.ID = RandomOrderID("cond-")
+	}
+
+	c.Exchange = e.GetName()
+	c.Submit.Exchange = e.GetName()
+	c.Created = time.Now()
+
+	if !bot.registry.StoreValue(e.GetName(), OrderValue{
+		Submit:         c.Submit,
+		SubmitResponse: order.SubmitResponse{OrderID: c.ID}, // nolint: exhaustivestruct
+		UserData:       userData,
+		Detail:         order.Detail{}, // nolint: exhaustivestruct
+		Synthetic:      true,
+	}) {
+		return "", ErrOrdersAlreadyExists
+	}
+
+	if err := bot.conditionals.add(c, userData); err != nil {
+		return c.ID, err
+	}
+
+	bot.queueOrder(e, c.Detail(order.Pending))
+
+	return c.ID, nil
+}
+
+// CancelConditional cancels a pending conditional order.  An order that got
+// triggered and is being submitted can't be cancelled anymore, and
+// ErrConditionalNotFound is returned, but it isn't armed again should its
+// submission fail.
+func (bot *Keep) CancelConditional(exchangeOrName interface{}, id string) error {
+	e := bot.getExchange(exchangeOrName)
+
+	c, _, triggering, err := bot.conditionals.remove(id)
+	if errors.Is(err, ErrConditionalNotFound) {
+		return err
+	}
+
+	if triggering {
+		return multierr.Append(fmt.Errorf("%w: %s already triggered", ErrConditionalNotFound, id), err)
+	}
+
+	bot.queueOrder(e, c.Detail(order.Cancelled))
+
+	return err
+}
+
+// ConditionalOrders returns all pending conditional orders.
+func (bot *Keep) ConditionalOrders() []ConditionalOrder {
+	return bot.conditionals.list()
+}
+
+// loadConditionals restores persisted conditional orders into the registry.
+func (bot *Keep) loadConditionals() error {
+	xs, err := bot.conditionals.load()
+	if err != nil {
+		return err
+	}
+
+	for _, c := range xs {
+		bot.registry.StoreValue(c.Exchange, OrderValue{
+			Submit:         c.Submit,
+			SubmitResponse: order.SubmitResponse{OrderID: c.ID}, // nolint: exhaustivestruct
+			UserData:       nil,
+			Detail:         c.Detail(order.Pending),
+			Synthetic:      true,
+		})
+	}
+
+	return nil
+}
+
+// triggerConditionals submits the conditional orders triggered by price.
+// Submissions happen in the background so as not to block the exchange's
+// stream.  A conditional order stays in the book until its submission succeeds,
+// and is then closed, or fails permanently, and is then rejected.  After a
+// transient failure (see RetryPolicy) it's armed again, i.e. it gets triggered
+// again by the next price crossing its trigger.
+func (bot *Keep) triggerConditionals(e exchange.IBotExchange,
+	a asset.Item,
+	p currency.Pair,
+	price func(side order.Side) float64) {
+	xs, ys := bot.conditionals.triggered(e.GetName(), a, p, price)
+
+	for i, c := range xs {
+		go bot.submitConditional(e, c, ys[i])
+	}
+}
+
+func (bot *Keep) submitConditional(e exchange.IBotExchange, c ConditionalOrder, userData interface{}) {
+	_, err := bot.SubmitOrderUD(context.Background(), e, c.Submit, userData)
+	if err == nil {
+		if _, _, _, err := bot.conditionals.remove(c.ID); err != nil && !errors.Is(err, ErrConditionalNotFound) {
+			What(log.Error().Err(err).Str("id", c.ID), "unable to persist conditional orders")
+		}
+
+		bot.queueOrder(e, c.Detail(order.Closed))
+
+		return
+	}
+
+	What(log.Error().Err(err).Str("exchange", e.GetName()).Str("id", c.ID),
+		"unable to submit triggered conditional order")
+	bot.ReportEvent(ConditionalOrderErrorMetric, e.GetName())
+
+	if bot.retry.transient(err) {
+		if !bot.conditionals.rearm(c.ID) {
+			// Cancelled while being submitted.
+			bot.queueOrder(e, c.Detail(order.Cancelled))
+		}
+
+		return
+	}
+
+	if _, _, _, err := bot.conditionals.remove(c.ID); err != nil && !errors.Is(err, ErrConditionalNotFound) {
+		What(log.Error().Err(err).Str("id", c.ID), "unable to persist conditional orders")
+	}
+
+	bot.queueOrder(e, c.Detail(order.Rejected))
+}
+
+// priceFromBook returns the price a market order on the given side would get
+// first, i.e. the best ask for buys and the best bid for sells.
+func priceFromBook(book orderbook.Base) func(side order.Side) float64 {
+	return func(side order.Side) float64 {
+		levels := book.Bids
+		if isBuy(side) {
+			levels = book.Asks
+		}
+
+		if len(levels) == 0 {
+			return 0
+		}
+
+		return levels[0].Price
+	}
+}
@@ -0,0 +1,148 @@
+package dola_test
+
+import (
+	"testing"
+
+	"github.com/numeusxyz/dola"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/ticker"
+)
+
+func TestConditionalOrder_Triggered(t *testing.T) {
+	t.Parallel()
+
+	sell := order.Submit{Side: order.Sell} // nolint: exhaustivestruct
+	buy := order.Submit{Side: order.Buy}   // nolint: exhaustivestruct
+
+	for _, tc := range []struct {
+		name  string
+		c     dola.ConditionalOrder
+		price float64
+		want  bool
+	}{
+		{"sell stop above", dola.StopLoss(sell, 90), 91, false},
+		{"sell stop below", dola.StopLoss(sell, 90), 90, true},
+		{"buy stop below", dola.StopLoss(buy, 110), 109, false},
+		{"buy stop above", dola.StopLoss(buy, 110), 111, true},
+		{"sell take profit", dola.TakeProfit(sell, 110), 110, true},
+		{"buy take profit", dola.TakeProfit(buy, 90), 95, false},
+		{"no price", dola.TakeProfit(buy, 90), 0, false},
+	} {
+		if have := tc.c.Triggered(tc.price); have != tc.want {
+			t.Errorf("%s: have %v, want %v", tc.name, have, tc.want)
+		}
+	}
+}
+
+// nolint: exhaustivestruct
+func TestKeep_SubmitConditional(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k    dola.Keep
+		e    = newFakeExchange("fake")
+		pair = currency.NewPair(currency.BTC, currency.USDT)
+	)
+
+	id, err := k.SubmitConditional(e, dola.StopLoss(order.Submit{
+		Amount:    1,
+		Type:      order.Market,
+		Side:      order.Sell,
+		Pair:      pair,
+		AssetType: asset.Spot,
+	}, 90), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Updates are delivered by the exchange's event loop only.
+	if value, _ := k.GetOrderValue(e.GetName(), id); value.Detail.Status != "" {
+		t.Errorf("have status %s delivered outside the event loop", value.Detail.Status)
+	}
+
+	k.DeliverQueuedOrders(e)
+
+	value, ok := k.GetOrderValue(e.GetName(), id)
+	if !ok || !value.Synthetic || value.Detail.Status != order.Pending {
+		t.Errorf("unexpected registry value: %+v", value)
+	}
+
+	k.OnPrice(e, ticker.Price{Last: 95, Pair: pair, AssetType: asset.Spot})
+
+	if n := len(k.ConditionalOrders()); n != 1 {
+		t.Fatalf("have %d conditional orders, want 1", n)
+	}
+
+	k.OnPrice(e, ticker.Price{Last: 89, Pair: pair, AssetType: asset.Spot})
+
+	// The triggered order is submitted in the background.
+	waitFor(func() bool { return len(k.ConditionalOrders()) == 0 })
+
+	if xs := e.submissions(); len(xs) != 1 || xs[0].Type != order.Market || xs[0].Amount != 1 {
+		t.Errorf("unexpected submissions: %+v", xs)
+	}
+
+	k.DeliverQueuedOrders(e)
+
+	if value, _ := k.GetOrderValue(e.GetName(), id); value.Detail.Status != order.Closed {
+		t.Errorf("have status %s, want %s", value.Detail.Status, order.Closed)
+	}
+}
+
+// nolint: exhaustivestruct
+func TestKeep_SubmitConditional_TransientFailure(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k        dola.Keep
+		e        = newFakeExchange("fake")
+		pair     = currency.NewPair(currency.BTC, currency.USDT)
+		attempts = make(chan struct{}, 2)
+	)
+
+	e.reject = func(x order.Submit) error {
+		attempts <- struct{}{}
+		if len(attempts) == 1 {
+			return errTimeout
+		}
+
+		return nil
+	}
+
+	id, err := k.SubmitConditional(e, dola.StopLoss(order.Submit{
+		Amount:    1,
+		Type:      order.Market,
+		Side:      order.Sell,
+		Pair:      pair,
+		AssetType: asset.Spot,
+	}, 90), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k.OnPrice(e, ticker.Price{Last: 89, Pair: pair, AssetType: asset.Spot})
+	waitFor(func() bool { return len(attempts) == 1 })
+
+	// Still armed after the failed submission.
+	if xs := k.ConditionalOrders(); len(xs) != 1 || xs[0].ID != id {
+		t.Fatalf("unexpected conditional orders: %+v", xs)
+	}
+
+	waitFor(func() bool {
+		k.OnPrice(e, ticker.Price{Last: 88, Pair: pair, AssetType: asset.Spot})
+
+		return len(k.ConditionalOrders()) == 0
+	})
+
+	if n := len(e.submissions()); n != 1 {
+		t.Errorf("have %d submissions, want 1", n)
+	}
+
+	k.DeliverQueuedOrders(e)
+
+	if value, _ := k.GetOrderValue(e.GetName(), id); value.Detail.Status != order.Closed {
+		t.Errorf("have status %s, want %s", value.Detail.Status, order.Closed)
+	}
+}
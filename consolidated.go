@@ -0,0 +1,269 @@
+package dola
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/orderbook"
+)
+
+// +------------------+
+// | ConsolidatedBook |
+// +------------------+
+
+// ConsolidationOptions configures the books consolidated across exchanges.  See
+// Keep.ConsolidatedBook.
+type ConsolidationOptions struct {
+	// NetOfFees ranks levels by their price net of the exchange's taker fee,
+	// i.e. what a taker would actually get for bids and pay for asks.  Fees
+	// are looked up in the background and the books of exchanges whose fee
+	// isn't known yet are left out meanwhile.  See Keep.TakerFeeRate to look
+	// them up beforehand.
+	NetOfFees bool
+	// Depth is the number of levels taken from each exchange, all if zero.
+	Depth int
+	// MaxAge, if non-zero, leaves out the books of exchanges that haven't been
+	// updated for that long.
+	MaxAge time.Duration
+}
+
+// VenueLevel is a level of an exchange's book within a ConsolidatedBook.
+type VenueLevel struct {
+	orderbook.Item
+	Exchange string
+	// EffectivePrice is Price net of fees, if so configured, or Price.
+	EffectivePrice float64
+}
+
+// ConsolidatedBook merges the books of an instrument across exchanges.  Bids
+// and asks are sorted best first by effective price.  It is never modified once
+// built, so it can be read from any goroutine.
+type ConsolidatedBook struct {
+	Asset asset.Item
+	Pair  currency.Pair
+	Bids  []VenueLevel
+	Asks  []VenueLevel
+	// Updated is when the book was last rebuilt.
+	Updated time.Time
+}
+
+func (b ConsolidatedBook) BestBid() (VenueLevel, bool) {
+	if len(b.Bids) == 0 {
+		return VenueLevel{}, false // nolint: exhaustivestruct
+	}
+
+	return b.Bids[0], true
+}
+
+func (b ConsolidatedBook) BestAsk() (VenueLevel, bool) {
+	if len(b.Asks) == 0 {
+		return VenueLevel{}, false // nolint: exhaustivestruct
+	}
+
+	return b.Asks[0], true
+}
+
+// Crossed reports whether the best bid is above the best ask, both at their
+// effective prices, i.e. whether there is an arbitrage across exchanges.
+func (b ConsolidatedBook) Crossed() bool {
+	bid, okBid := b.BestBid()
+	ask, okAsk := b.BestAsk()
+
+	return okBid && okAsk && bid.EffectivePrice > ask.EffectivePrice
+}
+
+// top returns what identifies the top of the book.
+func (b ConsolidatedBook) top() [2]VenueLevel {
+	bid, _ := b.BestBid()
+	ask, _ := b.BestAsk()
+
+	return [2]VenueLevel{bid, ask}
+}
+
+// ConsolidatedBookObserver is implemented by strategies interested in
+// consolidated books.  OnConsolidatedBook is called whenever the best bid or
+// ask across exchanges changes, from the thread of the exchange whose book
+// caused the change.  Calls for a single instrument never overlap, and a book
+// superseded meanwhile by another exchange's update is skipped.
+type ConsolidatedBookObserver interface {
+	OnConsolidatedBook(k *Keep, x ConsolidatedBook) error
+}
+
+// consolidation holds the per-exchange books of an instrument.
+type consolidation struct {
+	mu     sync.Mutex
+	venues map[string]venue
+	top    [2]VenueLevel
+	// changes counts the changes of top.  Strategies are notified without
+	// holding mu, one at a time under notifying, of changes newer than the
+	// one notified.
+	changes   uint64
+	notifying sync.Mutex
+	notified  uint64
+}
+
+func newConsolidation() *consolidation {
+	return &consolidation{
+		mu:        sync.Mutex{},
+		venues:    make(map[string]venue),
+		top:       [2]VenueLevel{},
+		changes:   0,
+		notifying: sync.Mutex{},
+		notified:  0,
+	}
+}
+
+type venue struct {
+	exchange exchange.IBotExchange
+	book     BookSnapshot
+}
+
+// +--------------------------+
+// | Keep: Consolidated books |
+// +--------------------------+
+
+// ConsolidatedBook returns the latest book of an instrument consolidated across
+// exchanges.  Books are consolidated only if Keep was built with
+// KeepBuilder.ConsolidateBooks.
+func (bot *Keep) ConsolidatedBook(a asset.Item, p currency.Pair) (ConsolidatedBook, bool) {
+	if x, ok := bot.consolidated.Load(newMarketKey("", a, p)); ok {
+		if book, ok := x.(ConsolidatedBook); ok {
+			return book, true
+		}
+	}
+
+	return ConsolidatedBook{}, false // nolint: exhaustivestruct
+}
+
+// consolidate merges x into the consolidated book of its instrument and
+// notifies strategies if the top of the book changed.
+func (bot *Keep) consolidate(e exchange.IBotExchange, x BookSnapshot) {
+	if bot.consolidation == nil {
+		return
+	}
+
+	key := newMarketKey("", x.Asset, x.Pair)
+
+	pointer, ok := bot.consolidations.Load(key)
+	if !ok {
+		pointer, _ = bot.consolidations.LoadOrStore(key, newConsolidation())
+	}
+
+	c, ok := pointer.(*consolidation)
+	if !ok {
+		panic("cast failed")
+	}
+
+	c.mu.Lock()
+	c.venues[e.GetName()] = venue{exchange: e, book: x}
+
+	book := bot.buildConsolidatedBook(x.Asset, x.Pair, c.venues)
+	bot.consolidated.Store(key, book)
+
+	top := book.top()
+	changed := top != c.top
+
+	if changed {
+		c.top = top
+		c.changes++
+	}
+
+	change := c.changes
+	c.mu.Unlock()
+
+	if !changed {
+		return
+	}
+
+	c.notifying.Lock()
+	defer c.notifying.Unlock()
+
+	if change <= c.notified {
+		return
+	}
+
+	c.notified = change
+
+	if err := bot.Root.OnConsolidatedBook(bot, book); err != nil {
+		What(log.Warn().Err(err).Str("pair", x.Pair.String()), "OnConsolidatedBook failed")
+	}
+}
+
+func (bot *Keep) buildConsolidatedBook(a asset.Item, p currency.Pair, venues map[string]venue) ConsolidatedBook {
+	opts := *bot.consolidation
+	book := ConsolidatedBook{
+		Asset:   a,
+		Pair:    p,
+		Bids:    []VenueLevel{},
+		Asks:    []VenueLevel{},
+		Updated: time.Now(),
+	}
+
+	for name, v := range venues {
+		x := v.book
+		if opts.MaxAge > 0 && x.Age() > opts.MaxAge {
+			continue
+		}
+
+		fee := 0.0
+		if opts.NetOfFees {
+			var ok bool
+			if fee, ok = bot.consolidationFee(v.exchange, x); !ok {
+				continue
+			}
+		}
+
+		bids, asks := x.Bids, x.Asks
+		if opts.Depth > 0 {
+			bids, asks = x.Depth(opts.Depth)
+		}
+
+		for _, y := range bids {
+			book.Bids = append(book.Bids, VenueLevel{Item: y, Exchange: name, EffectivePrice: y.Price * (1 - fee)})
+		}
+
+		for _, y := range asks {
+			book.Asks = append(book.Asks, VenueLevel{Item: y, Exchange: name, EffectivePrice: y.Price * (1 + fee)})
+		}
+	}
+
+	// Ties are broken by exchange name to keep the top stable.
+	sort.Slice(book.Bids, func(i, j int) bool {
+		x, y := book.Bids[i], book.Bids[j]
+		if x.EffectivePrice != y.EffectivePrice {
+			return x.EffectivePrice > y.EffectivePrice
+		}
+
+		return x.Exchange < y.Exchange
+	})
+	sort.Slice(book.Asks, func(i, j int) bool {
+		x, y := book.Asks[i], book.Asks[j]
+		if x.EffectivePrice != y.EffectivePrice {
+			return x.EffectivePrice < y.EffectivePrice
+		}
+
+		return x.Exchange < y.Exchange
+	})
+
+	return book
+}
+
+// consolidationFee returns the taker fee rate of an exchange for the instrument
+// of x, if known.
+func (bot *Keep) consolidationFee(e exchange.IBotExchange, x BookSnapshot) (float64, bool) {
+	price, ok := x.Mid()
+	if !ok {
+		if bid, ok := x.BestBid(); ok {
+			price = bid.Price
+		} else if ask, ok := x.BestAsk(); ok {
+			price = ask.Price
+		}
+	}
+
+	return bot.cachedTakerFeeRate(e, x.Asset, x.Pair, price)
+}
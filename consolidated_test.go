@@ -0,0 +1,229 @@
+package dola_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/numeusxyz/dola"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/orderbook"
+)
+
+// bookRecorder records the consolidated books it is given.
+type bookRecorder struct {
+	dola.VerboseStrategy
+
+	xs []dola.ConsolidatedBook
+}
+
+func (r *bookRecorder) OnConsolidatedBook(k *dola.Keep, x dola.ConsolidatedBook) error {
+	r.xs = append(r.xs, x)
+
+	return nil
+}
+
+// nolint: exhaustivestruct, funlen
+func TestKeep_ConsolidatedBook(t *testing.T) {
+	t.Parallel()
+
+	var (
+		pair = currency.NewPair(currency.BTC, currency.USDT)
+		a    = newFakeExchange("a")
+		b    = newFakeExchange("b")
+		book = func(bid, ask float64) orderbook.Base {
+			return orderbook.Base{
+				Bids:  []orderbook.Item{{Price: bid, Amount: 1}, {Price: bid - 1, Amount: 1}},
+				Asks:  []orderbook.Item{{Price: ask, Amount: 1}, {Price: ask + 1, Amount: 1}},
+				Pair:  pair,
+				Asset: asset.Spot,
+			}
+		}
+		top = func(x dola.ConsolidatedBook) (string, string) {
+			bid, _ := x.BestBid()
+			ask, _ := x.BestAsk()
+
+			return bid.Exchange, ask.Exchange
+		}
+	)
+
+	a.feeRate, b.feeRate = 0.001, 0.01
+
+	for _, netOfFees := range []bool{false, true} {
+		var (
+			k   = dola.Keep{Root: dola.NewRootStrategy()}
+			rec = &bookRecorder{}
+		)
+
+		k.Root.Add("rec", rec)
+		k.SetConsolidation(dola.ConsolidationOptions{NetOfFees: netOfFees, Depth: 1})
+
+		for _, e := range []*fakeExchange{a, b} {
+			if _, err := k.TakerFeeRate(context.Background(), e, asset.Spot, pair, 100); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		k.OnOrderBook(a, book(100, 101))
+		k.OnOrderBook(b, book(100.5, 102))
+		// The top is unchanged.
+		k.OnOrderBook(b, book(100.5, 102))
+
+		x, ok := k.ConsolidatedBook(asset.Spot, pair)
+		if !ok {
+			t.Fatal("have no book")
+		}
+
+		if len(x.Bids) != 2 || len(x.Asks) != 2 {
+			t.Errorf("have %d bids and %d asks, want one level per exchange", len(x.Bids), len(x.Asks))
+		}
+
+		bid, ask := top(x)
+
+		switch {
+		case !netOfFees && (bid != "b" || ask != "a"):
+			t.Errorf("have %s/%s, want b/a", bid, ask)
+		case netOfFees && (bid != "a" || ask != "a"):
+			// 100.5 * 0.99 is less than 100 * 0.999.
+			t.Errorf("have %s/%s net of fees, want a/a", bid, ask)
+		}
+
+		// Net of fees, b's book doesn't change the top.
+		want := 2
+		if netOfFees {
+			want = 1
+		}
+
+		if len(rec.xs) != want {
+			t.Errorf("have %d events, want %d", len(rec.xs), want)
+		}
+
+		if x.Crossed() {
+			t.Error("have crossed book")
+		}
+	}
+
+	// Without consolidation, there is no book.
+	var k dola.Keep
+
+	k.OnOrderBook(a, book(100, 101))
+
+	if _, ok := k.ConsolidatedBook(asset.Spot, pair); ok {
+		t.Error("unexpected book")
+	}
+}
+
+// nolint: exhaustivestruct
+func TestConsolidatedBook_Crossed(t *testing.T) {
+	t.Parallel()
+
+	x := dola.ConsolidatedBook{
+		Bids: []dola.VenueLevel{{Exchange: "a", EffectivePrice: 102}},
+		Asks: []dola.VenueLevel{{Exchange: "b", EffectivePrice: 101}},
+	}
+
+	if !x.Crossed() {
+		t.Error("have not crossed, want crossed")
+	}
+}
+
+// nolint: exhaustivestruct
+func TestKeep_ConsolidatedBook_UnknownFee(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k    = dola.Keep{Root: dola.NewRootStrategy()}
+		e    = newFakeExchange("fake")
+		pair = currency.NewPair(currency.BTC, currency.USDT)
+		book = orderbook.Base{
+			Bids:  []orderbook.Item{{Price: 100, Amount: 1}},
+			Asks:  []orderbook.Item{{Price: 101, Amount: 1}},
+			Pair:  pair,
+			Asset: asset.Spot,
+		}
+	)
+
+	e.feeRate = 0.01
+	e.feeErr = errors.New("timeout")
+
+	k.SetConsolidation(dola.ConsolidationOptions{NetOfFees: true})
+
+	// Failed lookups aren't cached.
+	if _, err := k.TakerFeeRate(context.Background(), e, asset.Spot, pair, 100); !errors.Is(err, dola.ErrUnknownFee) {
+		t.Errorf("have %v, want %v", err, dola.ErrUnknownFee)
+	}
+
+	e.mu.Lock()
+	e.feeErr = nil
+	e.mu.Unlock()
+
+	// Until its fee is known, the exchange is left out.
+	k.OnOrderBook(e, book)
+
+	if x, _ := k.ConsolidatedBook(asset.Spot, pair); len(x.Bids) != 0 || len(x.Asks) != 0 {
+		t.Errorf("have %+v, want an empty book", x)
+	}
+
+	// Meanwhile, it's looked up in the background.
+	waitFor(func() bool {
+		k.OnOrderBook(e, book)
+		x, _ := k.ConsolidatedBook(asset.Spot, pair)
+
+		return len(x.Bids) > 0
+	})
+
+	if x, _ := k.ConsolidatedBook(asset.Spot, pair); len(x.Bids) != 1 || x.Bids[0].EffectivePrice != 99 {
+		t.Errorf("unexpected book: %+v", x)
+	}
+}
+
+// bookBlocker blocks within OnConsolidatedBook until released.
+type bookBlocker struct {
+	dola.VerboseStrategy
+
+	called, release chan struct{}
+}
+
+func (r *bookBlocker) OnConsolidatedBook(k *dola.Keep, x dola.ConsolidatedBook) error {
+	r.called <- struct{}{}
+	<-r.release
+
+	return nil
+}
+
+// nolint: exhaustivestruct
+func TestKeep_ConsolidatedBook_SlowObserver(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k     = dola.Keep{Root: dola.NewRootStrategy()}
+		pair  = currency.NewPair(currency.BTC, currency.USDT)
+		a     = newFakeExchange("a")
+		b     = newFakeExchange("b")
+		rec   = &bookBlocker{called: make(chan struct{}), release: make(chan struct{})}
+		done  = make(chan struct{})
+		level = func(price float64) []orderbook.Item { return []orderbook.Item{{Price: price, Amount: 1}} }
+	)
+
+	k.Root.Add("rec", rec)
+	k.SetConsolidation(dola.ConsolidationOptions{})
+
+	go func() {
+		k.OnOrderBook(a, orderbook.Base{Bids: level(100), Asks: level(101), Pair: pair, Asset: asset.Spot})
+		close(done)
+	}()
+
+	<-rec.called
+
+	// b's book is consolidated while the observer is busy, as it doesn't change
+	// the top.
+	k.OnOrderBook(b, orderbook.Base{Bids: level(99), Asks: level(102), Pair: pair, Asset: asset.Spot})
+
+	if x, _ := k.ConsolidatedBook(asset.Spot, pair); len(x.Bids) != 2 {
+		t.Errorf("have %d bids, want both exchanges' books", len(x.Bids))
+	}
+
+	close(rec.release)
+	<-done
+}
@@ -0,0 +1,443 @@
+package dola
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/orderbook"
+)
+
+// orderEntry is the subset of exchange.IBotExchange Keep uses to manage orders.
+type orderEntry interface {
+	SubmitOrder(ctx context.Context, s *order.Submit) (order.SubmitResponse, error)
+	ModifyOrder(ctx context.Context, action *order.Modify) (order.Modify, error)
+	CancelOrder(ctx context.Context, o *order.Cancel) error
+	CancelAllOrders(ctx context.Context, orders *order.Cancel) (order.CancelAllResponse, error)
+	GetActiveOrders(ctx context.Context, getOrdersRequest *order.GetOrdersRequest) ([]order.Detail, error)
+	GetOrderInfo(ctx context.Context, orderID string, pair currency.Pair, assetType asset.Item) (order.Detail, error)
+	GetOrderHistory(ctx context.Context, getOrdersRequest *order.GetOrdersRequest) ([]order.Detail, error)
+}
+
+// entry returns where orders for an exchange should be sent to: the exchange
+// itself or, in dry-run mode, the paper trader.
+func (bot *Keep) entry(e exchange.IBotExchange) orderEntry {
+	if bot.Settings.EnableDryRun {
+		return paperExchange{
+			keep:     bot,
+			exchange: e,
+		}
+	}
+
+	return e
+}
+
+// +-------------+
+// | PaperTrader |
+// +-------------+
+
+var ErrPaperOrderNotFound = errors.New("dry run: order not found")
+
+// paperTrader keeps the orders placed in dry-run mode.  If simulateFills is set,
+// orders are matched against the latest order book: on submission and then on
+// every Keep.OnOrderBook.  The book itself is never depleted by simulated fills.
+type paperTrader struct {
+	simulateFills bool
+
+	mu     sync.Mutex
+	orders map[OrderKey]*order.Detail
+}
+
+// paperExchange implements orderEntry for a single exchange.  All resulting
+// order updates are queued and delivered through handleData, i.e. Keep.OnOrder
+// and Strategy.OnOrder, from the exchange's stream goroutine.
+type paperExchange struct {
+	keep     *Keep
+	exchange exchange.IBotExchange
+}
+
+func (p paperExchange) trader() *paperTrader {
+	return &p.keep.paper
+}
+
+func (p paperExchange) SubmitOrder(ctx context.Context, s *order.Submit) (order.SubmitResponse, error) {
+	if err := s.Validate(); err != nil {
+		return order.SubmitResponse{}, err // nolint: exhaustivestruct
+	}
+
+	now := time.Now()
+	// nolint: exhaustivestruct
+	x := &order.Detail{
+		ImmediateOrCancel: s.ImmediateOrCancel,
+		FillOrKill:        s.FillOrKill,
+		PostOnly:          s.PostOnly,
+		Price:             s.Price,
+		Amount:            s.Amount,
+		RemainingAmount:   s.Amount,
+		Exchange:          p.exchange.GetName(),
+		ID:                RandomOrderID("dry-"),
+		ClientOrderID:     s.ClientOrderID,
+		AccountID:         s.AccountID,
+		Type:              s.Type,
+		Side:              s.Side,
+		Status:            order.New,
+		AssetType:         s.AssetType,
+		Date:              now,
+		LastUpdated:       now,
+		Pair:              s.Pair,
+	}
+
+	t := p.trader()
+	t.mu.Lock()
+
+	if t.orders == nil {
+		t.orders = make(map[OrderKey]*order.Detail)
+	}
+
+	t.orders[OrderKey{ExchangeName: x.Exchange, OrderID: x.ID}] = x
+	t.mu.Unlock()
+
+	return order.SubmitResponse{ // nolint: exhaustivestruct
+		IsOrderPlaced: true,
+		OrderID:       x.ID,
+	}, nil
+}
+
+// acknowledge emits the first update of a newly submitted order and, if
+// enabled, matches it against the current order book.  It is called once the
+// order is stored in the registry so that its observers are notified.
+func (p paperExchange) acknowledge(ctx context.Context, orderID string) {
+	t := p.trader()
+	key := OrderKey{ExchangeName: p.exchange.GetName(), OrderID: orderID}
+
+	t.mu.Lock()
+	x, ok := t.orders[key]
+	var ack order.Detail
+	if ok {
+		ack = x.Copy()
+	}
+	t.mu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	p.emit(ack)
+
+	if !t.simulateFills {
+		return
+	}
+
+	book, err := p.book(ctx, ack.Pair, ack.AssetType)
+	if err != nil {
+		What(log.Warn().Err(err).Str("exchange", p.exchange.GetName()), "dry run: no order book to match against")
+
+		return
+	}
+
+	p.match(book)
+}
+
+// book returns the cached book for an instrument, falling back to fetching it.
+func (p paperExchange) book(ctx context.Context, pair currency.Pair, a asset.Item) (orderbook.Base, error) {
+	if book, ok := p.keep.OrderBook(p.exchange.GetName(), a, pair); ok {
+		return book.Base, nil
+	}
+
+	book, err := p.exchange.FetchOrderbook(ctx, pair, a)
+	if err != nil {
+		return orderbook.Base{}, err // nolint: exhaustivestruct
+	}
+
+	return *book, nil
+}
+
+// match fills the open orders for the book's pair as far as the book allows.
+func (p paperExchange) match(book orderbook.Base) {
+	t := p.trader()
+
+	var updates []order.Detail
+
+	t.mu.Lock()
+
+	for key, x := range t.orders {
+		if key.ExchangeName != p.exchange.GetName() || x.AssetType != book.Asset || !x.Pair.Equal(book.Pair) {
+			continue
+		}
+
+		if !fillFromBook(x, book) {
+			continue
+		}
+
+		if x.Status == order.Filled || x.Status == order.PartiallyCancelled || x.Status == order.Cancelled {
+			delete(t.orders, key)
+		}
+
+		updates = append(updates, x.Copy())
+	}
+
+	t.mu.Unlock()
+
+	for _, x := range updates {
+		p.emit(x)
+	}
+}
+
+// fillFromBook executes x against the opposite side of the book and reports
+// whether x changed.  Market and immediate-or-cancel orders never rest: their
+// unfilled remainder gets cancelled.
+func fillFromBook(x *order.Detail, book orderbook.Base) bool {
+	buy := x.Side == order.Buy || x.Side == order.Bid
+
+	levels := book.Bids
+	if buy {
+		levels = book.Asks
+	}
+
+	var (
+		amount   float64
+		notional float64
+	)
+
+	for _, level := range levels {
+		remaining := x.Amount - x.ExecutedAmount - amount
+		if remaining <= 0 {
+			break
+		}
+
+		if x.Type == order.Limit && ((buy && level.Price > x.Price) || (!buy && level.Price < x.Price)) {
+			break
+		}
+
+		fill := math.Min(remaining, level.Amount)
+		amount += fill
+		notional += fill * level.Price
+	}
+
+	immediate := x.Type == order.Market || x.ImmediateOrCancel || x.FillOrKill
+
+	if x.FillOrKill && amount < x.Amount-x.ExecutedAmount {
+		amount, notional = 0, 0
+	}
+
+	if amount <= 0 && !immediate {
+		return false
+	}
+
+	if amount > 0 {
+		executed := x.ExecutedAmount + amount
+		x.AverageExecutedPrice = (x.AverageExecutedPrice*x.ExecutedAmount + notional) / executed
+		x.ExecutedAmount = executed
+		x.RemainingAmount = x.Amount - executed
+		x.Cost = x.AverageExecutedPrice * executed
+	}
+
+	switch {
+	case x.RemainingAmount <= 0:
+		x.Status = order.Filled
+	case immediate && x.ExecutedAmount > 0:
+		x.Status = order.PartiallyCancelled
+	case immediate:
+		x.Status = order.Cancelled
+	default:
+		x.Status = order.PartiallyFilled
+	}
+
+	x.LastUpdated = time.Now()
+
+	return true
+}
+
+func (p paperExchange) emit(x order.Detail) {
+	p.keep.queueOrder(p.exchange, x)
+}
+
+func (p paperExchange) ModifyOrder(ctx context.Context, action *order.Modify) (order.Modify, error) {
+	t := p.trader()
+	key := OrderKey{ExchangeName: p.exchange.GetName(), OrderID: action.ID}
+
+	t.mu.Lock()
+
+	x, ok := t.orders[key]
+	if !ok {
+		t.mu.Unlock()
+
+		return *action, fmt.Errorf("%w: %s", ErrPaperOrderNotFound, action.ID)
+	}
+
+	if action.Price > 0 {
+		x.Price = action.Price
+	}
+
+	if action.Amount > 0 {
+		x.Amount = action.Amount
+		x.RemainingAmount = x.Amount - x.ExecutedAmount
+	}
+
+	x.Status = order.Active
+	x.LastUpdated = time.Now()
+	update := x.Copy()
+
+	t.mu.Unlock()
+
+	p.emit(update)
+
+	if t.simulateFills {
+		if book, err := p.book(ctx, update.Pair, update.AssetType); err == nil {
+			p.match(book)
+		}
+	}
+
+	return *action, nil
+}
+
+func (p paperExchange) CancelOrder(ctx context.Context, o *order.Cancel) error {
+	t := p.trader()
+
+	t.mu.Lock()
+
+	var (
+		found *order.Detail
+		key   OrderKey
+	)
+
+	for k, x := range t.orders {
+		if k.ExchangeName == p.exchange.GetName() &&
+			((o.ID != "" && x.ID == o.ID) || (o.ID == "" && o.ClientOrderID != "" && x.ClientOrderID == o.ClientOrderID)) {
+			found, key = x, k
+
+			break
+		}
+	}
+
+	if found == nil {
+		t.mu.Unlock()
+
+		return fmt.Errorf("%w: %s", ErrPaperOrderNotFound, o.ID)
+	}
+
+	delete(t.orders, key)
+	update := cancelled(found)
+
+	t.mu.Unlock()
+
+	p.emit(update)
+
+	return nil
+}
+
+func (p paperExchange) CancelAllOrders(ctx context.Context, o *order.Cancel) (order.CancelAllResponse, error) {
+	t := p.trader()
+	resp := order.CancelAllResponse{
+		Status: make(map[string]string),
+		Count:  0,
+	}
+
+	var updates []order.Detail
+
+	t.mu.Lock()
+
+	for key, x := range t.orders {
+		if key.ExchangeName != p.exchange.GetName() ||
+			(o.AssetType != "" && x.AssetType != o.AssetType) ||
+			(!o.Pair.IsEmpty() && !x.Pair.Equal(o.Pair)) {
+			continue
+		}
+
+		delete(t.orders, key)
+		updates = append(updates, cancelled(x))
+		resp.Status[x.ID] = string(order.Cancelled)
+		resp.Count++
+	}
+
+	t.mu.Unlock()
+
+	for _, x := range updates {
+		p.emit(x)
+	}
+
+	return resp, nil
+}
+
+func (p paperExchange) GetActiveOrders(ctx context.Context, r *order.GetOrdersRequest) ([]order.Detail, error) {
+	t := p.trader()
+
+	var xs []order.Detail
+
+	t.mu.Lock()
+
+	for key, x := range t.orders {
+		if key.ExchangeName == p.exchange.GetName() &&
+			(r.AssetType == "" || x.AssetType == r.AssetType) &&
+			(r.OrderID == "" || strings.EqualFold(x.ID, r.OrderID)) {
+			xs = append(xs, x.Copy())
+		}
+	}
+
+	t.mu.Unlock()
+
+	order.FilterOrdersByType(&xs, r.Type)
+	order.FilterOrdersBySide(&xs, r.Side)
+	order.FilterOrdersByCurrencies(&xs, r.Pairs)
+
+	return xs, nil
+}
+
+func cancelled(x *order.Detail) order.Detail {
+	x.Status = cancelledStatus(*x)
+
+	x.LastUpdated = time.Now()
+
+	return x.Copy()
+}
+
+// cancelledStatus returns the status of x once cancelled.
+func cancelledStatus(x order.Detail) order.Status {
+	if x.ExecutedAmount > 0 {
+		return order.PartiallyCancelled
+	}
+
+	return order.Cancelled
+}
+
+// GetOrderInfo returns an open order or, as closed orders are forgotten by the
+// paper trader, the last update emitted for it.
+func (p paperExchange) GetOrderInfo(ctx context.Context,
+	orderID string,
+	pair currency.Pair,
+	assetType asset.Item) (order.Detail, error) {
+	t := p.trader()
+	key := OrderKey{ExchangeName: p.exchange.GetName(), OrderID: orderID}
+
+	t.mu.Lock()
+	x, ok := t.orders[key]
+	var detail order.Detail
+	if ok {
+		detail = x.Copy()
+	}
+	t.mu.Unlock()
+
+	if ok {
+		return detail, nil
+	}
+
+	if value, ok := p.keep.GetOrderValue(p.exchange.GetName(), orderID); ok && value.Detail.Status != "" {
+		return value.Detail, nil
+	}
+
+	return order.Detail{}, fmt.Errorf("%w: %s", ErrPaperOrderNotFound, orderID) // nolint: exhaustivestruct
+}
+
+// GetOrderHistory returns nothing as the paper trader forgets closed orders.
+func (p paperExchange) GetOrderHistory(ctx context.Context, r *order.GetOrdersRequest) ([]order.Detail, error) {
+	return nil, nil
+}
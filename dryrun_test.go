@@ -0,0 +1,93 @@
+package dola_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/numeusxyz/dola"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+)
+
+// nolint: exhaustivestruct
+func TestKeep_DryRun(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k           dola.Keep
+		e           = newFakeExchange("fake")
+		ctx, cancel = context.WithCancel(context.Background())
+		pair        = currency.NewPair(currency.BTC, currency.USDT)
+		events      = make(chan order.Status, 2)
+	)
+
+	defer cancel()
+
+	k.Settings.EnableDryRun = true
+
+	record := func(_ *dola.Keep, _ exchange.IBotExchange, x order.Detail) {
+		events <- x.Status
+	}
+
+	resp, err := k.SubmitOrderUD(ctx, e, order.Submit{
+		Price:     100,
+		Amount:    1,
+		Type:      order.Limit,
+		Side:      order.Buy,
+		Pair:      pair,
+		AssetType: asset.Spot,
+	}, dola.Slots{OnAcknowledgedSlot: record, OnCancelledSlot: record})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !resp.IsOrderPlaced || resp.OrderID == "" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+
+	xs, err := k.GetActiveOrders(ctx, e, order.GetOrdersRequest{Pairs: currency.Pairs{pair}, AssetType: asset.Spot})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(xs) != 1 || xs[0].ID != resp.OrderID {
+		t.Errorf("have %+v, want a single order with ID %s", xs, resp.OrderID)
+	}
+
+	if err := k.CancelOrder(ctx, e, order.Cancel{ID: resp.OrderID}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Updates are delivered by the exchange's event loop only.
+	if len(events) != 0 {
+		t.Fatalf("have %d updates delivered outside the event loop, want none", len(events))
+	}
+
+	go func() {
+		_ = dola.Loop(ctx, &k, e, &k.Root)
+	}()
+
+	var statuses []order.Status
+
+	for len(statuses) < 2 {
+		select {
+		case x := <-events:
+			statuses = append(statuses, x)
+		case <-time.After(time.Second):
+			t.Fatalf("timed out with updates %v", statuses)
+		}
+	}
+
+	if diff := cmp.Diff([]order.Status{order.New, order.Cancelled}, statuses); diff != "" {
+		t.Error(diff)
+	}
+
+	// Nothing ever reached the exchange.
+	if len(e.submitted) != 0 || len(e.cancelled) != 0 {
+		t.Errorf("have %d submitted and %d cancelled orders, want none", len(e.submitted), len(e.cancelled))
+	}
+}
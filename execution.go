@@ -0,0 +1,569 @@
+package dola
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/account"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/fill"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/orderbook"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/stream"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/ticker"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/trade"
+	"go.uber.org/multierr"
+)
+
+// +-----------+
+// | Execution |
+// +-----------+
+
+var (
+	ErrInvalidExecution  = errors.New("execution needs a positive amount, duration and number of slices")
+	ErrExecutionFinished = errors.New("execution already finished")
+)
+
+type ExecutionAlgorithm int
+
+const (
+	// TWAP slices the parent order evenly over time.
+	TWAP ExecutionAlgorithm = iota
+	// VWAP slices the parent order proportionally to the market volume observed
+	// (via OnTrade) in the preceding slice, relative to the average so far.
+	VWAP
+)
+
+type ExecutionState int
+
+const (
+	ExecutionRunning ExecutionState = iota
+	ExecutionPaused
+	ExecutionDone
+	ExecutionCancelled
+)
+
+// ExecutionParams describes a parent order.  Child orders are market orders,
+// unless LimitPrice is set, in which case they are immediate-or-cancel limit
+// orders at that price.
+type ExecutionParams struct {
+	Algorithm  ExecutionAlgorithm
+	Pair       currency.Pair
+	AssetType  asset.Item
+	Side       order.Side
+	Amount     float64
+	LimitPrice float64
+	Duration   time.Duration
+	Slices     int
+	// OnProgress, if set, is called after every slice and fill.
+	OnProgress func(ExecutionProgress)
+}
+
+type ExecutionProgress struct {
+	State        ExecutionState
+	Slice        int
+	Slices       int
+	Executed     float64
+	Remaining    float64
+	AveragePrice float64
+	ArrivalPrice float64
+	// Slippage is the relative difference between AveragePrice and
+	// ArrivalPrice, positive when adverse.  It is zero if no arrival price is
+	// known.
+	Slippage float64
+}
+
+// Execution slices a parent order into child orders submitted over a time
+// window.  It is registered as a strategy in Keep.Root while running in order to
+// observe trades, and as the UserData of its child orders in order to observe
+// fills.
+type Execution struct {
+	ID     string
+	Params ExecutionParams
+
+	keep     *Keep
+	exchange exchange.IBotExchange
+	cancel   context.CancelFunc
+	resume   chan struct{}
+
+	mu    sync.Mutex
+	state ExecutionState
+	// cancelling is set by Cancel, so that child orders placed meanwhile get
+	// cancelled as well.
+	cancelling bool
+	slice      int
+	fraction   float64
+	arrival    float64
+	executed   float64
+	cost       float64
+	children   map[string]*executionChild
+	// Market volume observed in the current slice and in all previous ones.
+	volume      float64
+	totalVolume float64
+}
+
+type executionChild struct {
+	amount   float64
+	executed float64
+	closed   bool
+}
+
+// Execute starts executing a parent order in the background.  Cancelling ctx
+// stops the execution as ExecutionCancelled, but leaves outstanding child orders
+// alone.
+func (bot *Keep) Execute(ctx context.Context, exchangeOrName interface{}, params ExecutionParams) (*Execution, error) {
+	if params.Amount <= 0 || params.Duration <= 0 || params.Slices <= 0 {
+		return nil, ErrInvalidExecution
+	}
+
+	if err := bot.checkHalted(); err != nil {
+		return nil, err
+	}
+
+	e := bot.getExchange(exchangeOrName)
+	arrival, _ := bot.lastPrice(e.GetName(), params.AssetType, params.Pair)
+	ctx, cancel := context.WithCancel(ctx)
+
+	x := &Execution{ // nolint: exhaustivestruct
+		ID:       RandomOrderID("exec-"),
+		Params:   params,
+		keep:     bot,
+		exchange: e,
+		cancel:   cancel,
+		resume:   make(chan struct{}),
+		state:    ExecutionRunning,
+		arrival:  arrival,
+		children: make(map[string]*executionChild),
+	}
+
+	bot.Root.Add(x.ID, x)
+
+	go x.run(ctx)
+
+	return x, nil
+}
+
+// Progress returns a snapshot of the execution's progress.
+func (x *Execution) Progress() ExecutionProgress {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	return x.progress()
+}
+
+// Pause stops submitting child orders.  The clock stops as well, i.e. the
+// remaining slices are submitted after Resume.
+func (x *Execution) Pause() error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	switch x.state {
+	case ExecutionRunning:
+		x.state = ExecutionPaused
+	case ExecutionPaused:
+	case ExecutionDone, ExecutionCancelled:
+		return ErrExecutionFinished
+	}
+
+	return nil
+}
+
+func (x *Execution) Resume() error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	switch x.state {
+	case ExecutionPaused:
+		x.state = ExecutionRunning
+		close(x.resume)
+		x.resume = make(chan struct{})
+	case ExecutionRunning:
+	case ExecutionDone, ExecutionCancelled:
+		return ErrExecutionFinished
+	}
+
+	return nil
+}
+
+// Cancel stops the execution and cancels its outstanding child orders,
+// including one being placed.
+func (x *Execution) Cancel(ctx context.Context) error {
+	x.mu.Lock()
+
+	if x.state == ExecutionDone || x.state == ExecutionCancelled {
+		x.mu.Unlock()
+
+		return ErrExecutionFinished
+	}
+
+	x.state = ExecutionCancelled
+	x.cancelling = true
+	x.cancel()
+
+	ids := make([]string, 0, len(x.children))
+
+	for id, child := range x.children {
+		if !child.closed {
+			ids = append(ids, id)
+		}
+	}
+
+	x.mu.Unlock()
+
+	x.finish()
+
+	var err error
+
+	for _, id := range ids {
+		// nolint: exhaustivestruct
+		err = multierr.Append(err, x.keep.CancelOrder(ctx, x.exchange, order.Cancel{
+			ID:        id,
+			Side:      x.Params.Side,
+			AssetType: x.Params.AssetType,
+			Pair:      x.Params.Pair,
+		}))
+	}
+
+	return err
+}
+
+func (x *Execution) run(ctx context.Context) {
+	interval := x.Params.Duration / time.Duration(x.Params.Slices)
+
+	for {
+		x.mu.Lock()
+		state, resume := x.state, x.resume
+		x.mu.Unlock()
+
+		switch state {
+		case ExecutionPaused:
+			select {
+			case <-resume:
+			case <-ctx.Done():
+				x.stop()
+
+				return
+			}
+
+			continue
+		case ExecutionDone, ExecutionCancelled:
+			return
+		case ExecutionRunning:
+		}
+
+		if last := x.submitSlice(ctx); last {
+			x.complete()
+
+			return
+		}
+
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			x.stop()
+
+			return
+		}
+	}
+}
+
+// stop finishes the execution as cancelled once its context is done, unless it
+// finished already.
+func (x *Execution) stop() {
+	x.mu.Lock()
+
+	if x.state == ExecutionDone || x.state == ExecutionCancelled {
+		x.mu.Unlock()
+
+		return
+	}
+
+	x.state = ExecutionCancelled
+	x.mu.Unlock()
+
+	x.finish()
+}
+
+// submitSlice submits the child order of the next slice and reports whether it
+// was the last one.
+func (x *Execution) submitSlice(ctx context.Context) bool {
+	x.mu.Lock()
+
+	n := x.Params.Slices
+	weight := 1.0
+
+	if x.Params.Algorithm == VWAP {
+		x.totalVolume += x.volume
+
+		if average := x.totalVolume / float64(x.slice+1); average > 0 {
+			weight = x.volume / average
+		}
+
+		x.volume = 0
+	}
+
+	x.fraction += (1 - x.fraction) / float64(n-x.slice) * weight
+	if x.fraction > 1 || x.slice == n-1 {
+		x.fraction = 1
+	}
+
+	x.slice++
+	amount := x.Params.Amount*x.fraction - x.executed - x.outstanding()
+	last := x.slice == n
+
+	x.mu.Unlock()
+
+	if amount > 0 {
+		x.submitChild(ctx, amount)
+	}
+
+	x.notify()
+
+	return last
+}
+
+func (x *Execution) submitChild(ctx context.Context, amount float64) {
+	s := order.Submit{ // nolint: exhaustivestruct
+		Amount:    amount,
+		Type:      order.Market,
+		Side:      x.Params.Side,
+		Pair:      x.Params.Pair,
+		AssetType: x.Params.AssetType,
+	}
+
+	if x.Params.LimitPrice > 0 {
+		s.Type = order.Limit
+		s.Price = x.Params.LimitPrice
+		s.ImmediateOrCancel = true
+	}
+
+	resp, err := x.keep.SubmitOrderUD(ctx, x.exchange, s, x)
+	if err != nil {
+		What(log.Error().Err(err).Str("exchange", x.exchange.GetName()).Str("execution", x.ID),
+			"unable to submit child order")
+
+		return
+	}
+
+	// Updates may have come in before SubmitOrderUD returned, e.g. in dry-run
+	// mode.
+	x.mu.Lock()
+	child, ok := x.children[resp.OrderID]
+	if !ok {
+		child = &executionChild{amount: amount, executed: 0, closed: false}
+		x.children[resp.OrderID] = child
+	}
+	cancel := x.cancelling && !child.closed
+	x.mu.Unlock()
+
+	if !cancel {
+		return
+	}
+
+	// Cancel missed the child, as it was being placed.  ctx is done by now.
+	// nolint: exhaustivestruct
+	if err := x.keep.CancelOrder(context.Background(), x.exchange, order.Cancel{
+		ID:        resp.OrderID,
+		Side:      x.Params.Side,
+		AssetType: x.Params.AssetType,
+		Pair:      x.Params.Pair,
+	}); err != nil {
+		What(log.Error().Err(err).Str("exchange", x.exchange.GetName()).Str("execution", x.ID),
+			"unable to cancel child order placed while cancelling")
+	}
+}
+
+// complete finishes the execution once the last slice is submitted and no child
+// orders are outstanding anymore.
+func (x *Execution) complete() {
+	x.mu.Lock()
+
+	if x.state != ExecutionRunning || x.slice < x.Params.Slices || x.outstanding() > 0 {
+		x.mu.Unlock()
+
+		return
+	}
+
+	x.state = ExecutionDone
+	x.cancel()
+	x.mu.Unlock()
+
+	x.finish()
+}
+
+func (x *Execution) finish() {
+	_, _ = x.keep.Root.Delete(x.ID)
+	x.notify()
+}
+
+func (x *Execution) notify() {
+	if x.Params.OnProgress != nil {
+		x.Params.OnProgress(x.Progress())
+	}
+}
+
+// outstanding returns the unexecuted amount of live child orders.
+func (x *Execution) outstanding() float64 {
+	var sum float64
+
+	for _, child := range x.children {
+		if !child.closed {
+			sum += child.amount - child.executed
+		}
+	}
+
+	return sum
+}
+
+func (x *Execution) progress() ExecutionProgress {
+	p := ExecutionProgress{
+		State:        x.state,
+		Slice:        x.slice,
+		Slices:       x.Params.Slices,
+		Executed:     x.executed,
+		Remaining:    x.Params.Amount - x.executed,
+		AveragePrice: 0,
+		ArrivalPrice: x.arrival,
+		Slippage:     0,
+	}
+
+	if x.executed > 0 {
+		p.AveragePrice = x.cost / x.executed
+	}
+
+	if p.AveragePrice > 0 && p.ArrivalPrice > 0 {
+		p.Slippage = (p.AveragePrice - p.ArrivalPrice) / p.ArrivalPrice
+		if !isBuy(x.Params.Side) {
+			p.Slippage = -p.Slippage
+		}
+	}
+
+	return p
+}
+
+// update accounts for a child order update.  Closed children are kept around
+// in case the update arrived before SubmitOrderUD returned.
+func (x *Execution) update(d order.Detail, closed bool) {
+	x.mu.Lock()
+
+	child, ok := x.children[d.ID]
+	if !ok {
+		child = &executionChild{amount: d.Amount, executed: 0, closed: false}
+		x.children[d.ID] = child
+	}
+
+	if executed := executedAmount(d); executed > child.executed {
+		price := d.AverageExecutedPrice
+		if price == 0 {
+			price = d.Price
+		}
+
+		x.executed += executed - child.executed
+		x.cost += (executed - child.executed) * price
+		child.executed = executed
+	}
+
+	child.closed = child.closed || closed
+
+	x.mu.Unlock()
+
+	x.notify()
+
+	if closed {
+		x.complete()
+	}
+}
+
+// +-------------------------+
+// | Execution: observations |
+// +-------------------------+
+
+func (x *Execution) OnPartiallyFilled(k *Keep, e exchange.IBotExchange, d order.Detail, delta float64) {
+	x.update(d, false)
+}
+
+func (x *Execution) OnFilled(k *Keep, e exchange.IBotExchange, d order.Detail) {
+	x.update(d, true)
+}
+
+func (x *Execution) OnCancelled(k *Keep, e exchange.IBotExchange, d order.Detail) {
+	x.update(d, true)
+}
+
+func (x *Execution) OnRejected(k *Keep, e exchange.IBotExchange, d order.Detail) {
+	x.update(d, true)
+}
+
+func (x *Execution) OnExpired(k *Keep, e exchange.IBotExchange, d order.Detail) {
+	x.update(d, true)
+}
+
+// +--------------------+
+// | Strategy interface |
+// +--------------------+
+
+func (x *Execution) Init(ctx context.Context, k *Keep, e exchange.IBotExchange) error {
+	return nil
+}
+
+func (x *Execution) OnFunding(k *Keep, e exchange.IBotExchange, y stream.FundingData) error {
+	return nil
+}
+
+func (x *Execution) OnPrice(k *Keep, e exchange.IBotExchange, y ticker.Price) error {
+	return nil
+}
+
+func (x *Execution) OnKline(k *Keep, e exchange.IBotExchange, y stream.KlineData) error {
+	return nil
+}
+
+func (x *Execution) OnOrderBook(k *Keep, e exchange.IBotExchange, y orderbook.Base) error {
+	return nil
+}
+
+func (x *Execution) OnOrder(k *Keep, e exchange.IBotExchange, y order.Detail) error {
+	return nil
+}
+
+func (x *Execution) OnModify(k *Keep, e exchange.IBotExchange, y order.Modify) error {
+	return nil
+}
+
+func (x *Execution) OnBalanceChange(k *Keep, e exchange.IBotExchange, y account.Change) error {
+	return nil
+}
+
+func (x *Execution) OnTrade(k *Keep, e exchange.IBotExchange, ys []trade.Data) error {
+	if e.GetName() != x.exchange.GetName() {
+		return nil
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	for _, y := range ys {
+		if y.AssetType == x.Params.AssetType && y.CurrencyPair.Equal(x.Params.Pair) {
+			x.volume += y.Amount
+		}
+	}
+
+	return nil
+}
+
+func (x *Execution) OnFill(k *Keep, e exchange.IBotExchange, y []fill.Data) error {
+	return nil
+}
+
+func (x *Execution) OnUnrecognized(k *Keep, e exchange.IBotExchange, y interface{}) error {
+	return nil
+}
+
+func (x *Execution) Deinit(k *Keep, e exchange.IBotExchange) error {
+	return nil
+}
@@ -0,0 +1,223 @@
+package dola_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/numeusxyz/dola"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/ticker"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/trade"
+)
+
+// fillSubmissions fills the first n orders submitted to e at price.
+// nolint: exhaustivestruct
+func fillSubmissions(t *testing.T, k *dola.Keep, e *fakeExchange, n int, price float64) []order.Submit {
+	t.Helper()
+
+	for i := 1; i <= n; i++ {
+		waitFor(func() bool { return len(e.submissions()) >= i })
+
+		xs := e.submissions()
+		if len(xs) < i {
+			t.Fatalf("have %d submissions, want %d", len(xs), i)
+		}
+
+		k.OnOrder(e, order.Detail{
+			ID:             fmt.Sprintf("%d", i),
+			Amount:         xs[i-1].Amount,
+			ExecutedAmount: xs[i-1].Amount,
+			Price:          price,
+			Status:         order.Filled,
+			Pair:           xs[i-1].Pair,
+			AssetType:      xs[i-1].AssetType,
+		})
+	}
+
+	return e.submissions()
+}
+
+// nolint: exhaustivestruct
+func TestKeep_Execute_TWAP(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k    dola.Keep
+		e    = newFakeExchange("fake")
+		pair = currency.NewPair(currency.BTC, currency.USDT)
+	)
+
+	k.OnPrice(e, ticker.Price{Last: 100, Pair: pair, AssetType: asset.Spot})
+
+	x, err := k.Execute(context.Background(), e, dola.ExecutionParams{
+		Algorithm: dola.TWAP,
+		Pair:      pair,
+		AssetType: asset.Spot,
+		Side:      order.Buy,
+		Amount:    1,
+		Duration:  40 * time.Millisecond,
+		Slices:    4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range fillSubmissions(t, &k, e, 4, 101) {
+		if s.Type != order.Market || s.Amount != 0.25 {
+			t.Errorf("unexpected submission: %+v", s)
+		}
+	}
+
+	waitFor(func() bool { return x.Progress().State == dola.ExecutionDone })
+
+	p := x.Progress()
+	if p.State != dola.ExecutionDone || p.Executed != 1 || p.Remaining != 0 || p.AveragePrice != 101 {
+		t.Errorf("unexpected progress: %+v", p)
+	}
+
+	if math.Abs(p.Slippage-0.01) > 1e-9 {
+		t.Errorf("have slippage %v, want 0.01", p.Slippage)
+	}
+
+	if _, err := k.Root.Get(x.ID); err == nil {
+		t.Error("finished execution is still registered")
+	}
+}
+
+// nolint: exhaustivestruct
+func TestKeep_Execute_VWAP(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k    dola.Keep
+		e    = newFakeExchange("fake")
+		pair = currency.NewPair(currency.BTC, currency.USDT)
+	)
+
+	x, err := k.Execute(context.Background(), e, dola.ExecutionParams{
+		Algorithm:  dola.VWAP,
+		Pair:       pair,
+		AssetType:  asset.Spot,
+		Side:       order.Sell,
+		Amount:     1,
+		LimitPrice: 90,
+		Duration:   400 * time.Millisecond,
+		Slices:     4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first slice has no volume to go by.  All the volume is traded during
+	// the second one, none during the third.
+	waitFor(func() bool { return len(e.submissions()) == 1 })
+
+	if err := x.OnTrade(&k, e, []trade.Data{{CurrencyPair: pair, AssetType: asset.Spot, Amount: 10}}); err != nil {
+		t.Fatal(err)
+	}
+
+	xs := fillSubmissions(t, &k, e, 3, 90)
+	for i, want := range []float64{0.25, 0.5, 0.25} {
+		if !xs[i].ImmediateOrCancel || xs[i].Price != 90 || math.Abs(xs[i].Amount-want) > 1e-9 {
+			t.Errorf("unexpected submission %d: %+v", i, xs[i])
+		}
+	}
+
+	waitFor(func() bool { return x.Progress().State == dola.ExecutionDone })
+
+	if p := x.Progress(); p.State != dola.ExecutionDone || p.Slippage != 0 {
+		t.Errorf("unexpected progress: %+v", p)
+	}
+}
+
+// nolint: exhaustivestruct
+func TestKeep_Execute_ContextCancelled(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k           dola.Keep
+		e           = newFakeExchange("fake")
+		ctx, cancel = context.WithCancel(context.Background())
+	)
+
+	x, err := k.Execute(ctx, e, dola.ExecutionParams{
+		Pair:      currency.NewPair(currency.BTC, currency.USDT),
+		AssetType: asset.Spot,
+		Side:      order.Buy,
+		Amount:    1,
+		Duration:  time.Hour,
+		Slices:    2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(func() bool { return len(e.submissions()) == 1 })
+	cancel()
+	waitFor(func() bool { return x.Progress().State == dola.ExecutionCancelled })
+
+	if state := x.Progress().State; state != dola.ExecutionCancelled {
+		t.Errorf("have state %v, want %v", state, dola.ExecutionCancelled)
+	}
+
+	if _, err := k.Root.Get(x.ID); !errors.Is(err, dola.ErrStrategyNotFound) {
+		t.Errorf("have %v, want %v", err, dola.ErrStrategyNotFound)
+	}
+
+	if err := x.Resume(); !errors.Is(err, dola.ErrExecutionFinished) {
+		t.Errorf("have %v, want %v", err, dola.ErrExecutionFinished)
+	}
+}
+
+// nolint: exhaustivestruct
+func TestExecution_Cancel_ChildInFlight(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k          dola.Keep
+		e          = newFakeExchange("fake")
+		submitting = make(chan struct{})
+		proceed    = make(chan struct{})
+	)
+
+	// Hold the first child order until the execution is cancelled.
+	e.reject = func(x order.Submit) error {
+		if e.counter == 0 {
+			close(submitting)
+			<-proceed
+		}
+
+		return nil
+	}
+
+	x, err := k.Execute(context.Background(), e, dola.ExecutionParams{
+		Pair:      currency.NewPair(currency.BTC, currency.USDT),
+		AssetType: asset.Spot,
+		Side:      order.Buy,
+		Amount:    1,
+		Duration:  time.Hour,
+		Slices:    2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	<-submitting
+
+	if err := x.Cancel(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	close(proceed)
+	waitFor(func() bool { return len(e.cancellations()) > 0 })
+
+	if xs := e.cancellations(); len(xs) != 1 || xs[0].ID != "1" {
+		t.Errorf("have cancellations %+v, want the child's", xs)
+	}
+}
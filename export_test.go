@@ -0,0 +1,44 @@
+package dola
+
+import (
+	"strings"
+	"time"
+
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+)
+
+// SetRetryPolicy lets tests configure retries without going through
+// KeepBuilder.Build.
+func (bot *Keep) SetRetryPolicy(p RetryPolicy) {
+	bot.retry = p
+}
+
+// SetRateLimits lets tests throttle an exchange without going through
+// KeepBuilder.Build.
+func (bot *Keep) SetRateLimits(exchangeName string, l RateLimits) {
+	if bot.schedulers == nil {
+		bot.schedulers = make(map[string]*OrderScheduler)
+	}
+
+	bot.schedulers[strings.ToLower(exchangeName)] = NewOrderScheduler(l)
+}
+
+func (bot *Keep) SetTickerStaleness(d time.Duration) {
+	bot.tickerStaleness = d
+}
+
+func (bot *Keep) SetConsolidation(opts ConsolidationOptions) {
+	bot.consolidation = &opts
+}
+
+// SetRiskChecks lets tests configure risk checks without going through
+// KeepBuilder.Build.
+func (bot *Keep) SetRiskChecks(cs ...RiskCheck) {
+	bot.risk = cs
+}
+
+// DeliverQueuedOrders delivers the order updates Keep queued for e, as its
+// event loop would.
+func (bot *Keep) DeliverQueuedOrders(e exchange.IBotExchange) {
+	bot.deliverQueuedOrders(e, &bot.Root)
+}
@@ -0,0 +1,211 @@
+package dola_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/thrasher-corp/gocryptotrader/common"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/kline"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/trade"
+)
+
+var errOrderNotFound = errors.New("order not found")
+
+// fakeExchange implements just enough of exchange.IBotExchange to drive Keep's
+// order entry points.  Calling any other method panics.
+type fakeExchange struct {
+	exchange.IBotExchange
+
+	name string
+
+	mu        sync.Mutex
+	counter   int
+	submitted []order.Submit
+	cancelled []order.Cancel
+	active    []order.Detail
+	history   []order.Detail
+	// reject, if set, fails submissions it returns an error for.
+	reject func(order.Submit) error
+	// cancelErr, if set, fails all cancellations.
+	cancelErr error
+	// onCancel, if set, is called after each successful cancellation, e.g. to
+	// emit an order update.
+	onCancel func(order.Cancel)
+	// pairs are the enabled spot pairs.
+	pairs        currency.Pairs
+	cancelledAll []order.Cancel
+	// cancelAllErr, if set, fails CancelAllOrders.
+	cancelAllErr error
+	// feeRate is the trading fee as a fraction of the notional.
+	feeRate float64
+	// feeErr, if set, fails fee lookups.
+	feeErr error
+	// ordersErr, if set, fails GetActiveOrders.
+	ordersErr error
+	candles   []kline.Candle
+	trades    []trade.Data
+}
+
+func newFakeExchange(name string) *fakeExchange {
+	return &fakeExchange{name: name} // nolint: exhaustivestruct
+}
+
+func (f *fakeExchange) GetName() string {
+	return f.name
+}
+
+func (f *fakeExchange) IsWebsocketEnabled() bool {
+	return false
+}
+
+func (f *fakeExchange) SubmitOrder(ctx context.Context, s *order.Submit) (order.SubmitResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.reject != nil {
+		if err := f.reject(*s); err != nil {
+			return order.SubmitResponse{}, err // nolint: exhaustivestruct
+		}
+	}
+
+	f.counter++
+	f.submitted = append(f.submitted, *s)
+
+	return order.SubmitResponse{ // nolint: exhaustivestruct
+		IsOrderPlaced: true,
+		OrderID:       fmt.Sprintf("%d", f.counter),
+	}, nil
+}
+
+func (f *fakeExchange) CancelOrder(ctx context.Context, c *order.Cancel) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.cancelErr != nil {
+		return f.cancelErr
+	}
+
+	f.cancelled = append(f.cancelled, *c)
+
+	if f.onCancel != nil {
+		f.mu.Unlock()
+		f.onCancel(*c)
+		f.mu.Lock()
+	}
+
+	return nil
+}
+
+func (f *fakeExchange) CancelAllOrders(ctx context.Context, c *order.Cancel) (order.CancelAllResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.cancelAllErr != nil {
+		return order.CancelAllResponse{}, f.cancelAllErr // nolint: exhaustivestruct
+	}
+
+	f.cancelledAll = append(f.cancelledAll, *c)
+
+	return order.CancelAllResponse{}, nil // nolint: exhaustivestruct
+}
+
+func (f *fakeExchange) GetAssetTypes(enabled bool) asset.Items {
+	return asset.Items{asset.Spot}
+}
+
+func (f *fakeExchange) GetEnabledPairs(a asset.Item) (currency.Pairs, error) {
+	return f.pairs, nil
+}
+
+func (f *fakeExchange) ModifyOrder(ctx context.Context, action *order.Modify) (order.Modify, error) {
+	return order.Modify{}, common.ErrFunctionNotSupported // nolint: exhaustivestruct
+}
+
+// GetOrderInfo looks orderID up in active.
+func (f *fakeExchange) GetOrderInfo(ctx context.Context,
+	orderID string,
+	pair currency.Pair,
+	assetType asset.Item) (order.Detail, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, x := range f.active {
+		if x.ID == orderID {
+			return x, nil
+		}
+	}
+
+	return order.Detail{}, errOrderNotFound // nolint: exhaustivestruct
+}
+
+func (f *fakeExchange) GetActiveOrders(ctx context.Context, r *order.GetOrdersRequest) ([]order.Detail, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.ordersErr != nil {
+		return nil, f.ordersErr
+	}
+
+	return append([]order.Detail{}, f.active...), nil
+}
+
+func (f *fakeExchange) GetOrderHistory(ctx context.Context, r *order.GetOrdersRequest) ([]order.Detail, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]order.Detail{}, f.history...), nil
+}
+
+func (f *fakeExchange) GetFeeByType(ctx context.Context, b *exchange.FeeBuilder) (float64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.feeErr != nil {
+		return 0, f.feeErr
+	}
+
+	return f.feeRate * b.PurchasePrice * b.Amount, nil
+}
+
+func (f *fakeExchange) GetHistoricCandles(ctx context.Context,
+	p currency.Pair,
+	a asset.Item,
+	start, end time.Time,
+	interval kline.Interval) (kline.Item, error) {
+	return kline.Item{Pair: p, Asset: a, Interval: interval, Candles: f.candles}, nil // nolint: exhaustivestruct
+}
+
+func (f *fakeExchange) GetHistoricTrades(ctx context.Context,
+	p currency.Pair,
+	a asset.Item,
+	start, end time.Time) ([]trade.Data, error) {
+	return f.trades, nil
+}
+
+func (f *fakeExchange) submissions() []order.Submit {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]order.Submit{}, f.submitted...)
+}
+
+func (f *fakeExchange) allCancellations() []order.Cancel {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]order.Cancel{}, f.cancelledAll...)
+}
+
+func (f *fakeExchange) cancellations() []order.Cancel {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]order.Cancel{}, f.cancelled...)
+}
@@ -0,0 +1,155 @@
+package dola
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+)
+
+// +------+
+// | Fees |
+// +------+
+
+const (
+	// feeTimeout bounds fee lookups made in the background.
+	feeTimeout = 10 * time.Second
+	// feeRetryInterval is how long a background fee lookup isn't repeated
+	// for.
+	feeRetryInterval = time.Minute
+)
+
+var ErrUnknownFee = errors.New("unable to get taker fee")
+
+// feeCache holds the taker fee rates known per instrument.  Only successful
+// lookups are cached.
+type feeCache struct {
+	mu    sync.Mutex
+	rates map[marketKey]float64
+	// attempts maps the instruments whose rate isn't known to when it was
+	// last looked up in the background.
+	attempts map[marketKey]time.Time
+}
+
+func (c *feeCache) load(key marketKey) (float64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	rate, ok := c.rates[key]
+
+	return rate, ok
+}
+
+func (c *feeCache) store(key marketKey, rate float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.rates == nil {
+		c.rates = make(map[marketKey]float64)
+	}
+
+	c.rates[key] = rate
+	delete(c.attempts, key)
+}
+
+// begin reports whether a background lookup of key is due and, if so, records
+// it.
+func (c *feeCache) begin(key marketKey, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if last, ok := c.attempts[key]; ok && now.Sub(last) < feeRetryInterval {
+		return false
+	}
+
+	if c.attempts == nil {
+		c.attempts = make(map[marketKey]time.Time)
+	}
+
+	c.attempts[key] = now
+
+	return true
+}
+
+// TakerFeeRate returns the taker fee of an exchange for an instrument as a
+// fraction of the notional, asking the exchange for the fee of buying a unit at
+// price.  Rates are cached once known, so that calling it up front saves later
+// lookups, e.g. when consolidating books net of fees.
+func (bot *Keep) TakerFeeRate(ctx context.Context,
+	exchangeOrName interface{},
+	a asset.Item,
+	p currency.Pair,
+	price float64) (float64, error) {
+	e := bot.getExchange(exchangeOrName)
+	key := newMarketKey(e.GetName(), a, p)
+
+	if rate, ok := bot.fees.load(key); ok {
+		return rate, nil
+	}
+
+	rate, err := takerFeeRate(ctx, e, p, price)
+	if err != nil {
+		return 0, err
+	}
+
+	bot.fees.store(key, rate)
+
+	return rate, nil
+}
+
+// cachedTakerFeeRate returns the taker fee rate of an exchange for an
+// instrument if it's known.  Otherwise it's looked up in the background, with a
+// timeout, and false is returned.  It never blocks, so it can be called from
+// stream goroutines.
+func (bot *Keep) cachedTakerFeeRate(e exchange.IBotExchange,
+	a asset.Item,
+	p currency.Pair,
+	price float64) (float64, bool) {
+	key := newMarketKey(e.GetName(), a, p)
+
+	if rate, ok := bot.fees.load(key); ok {
+		return rate, true
+	}
+
+	if bot.fees.begin(key, time.Now()) {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), feeTimeout)
+			defer cancel()
+
+			if _, err := bot.TakerFeeRate(ctx, e, a, p, price); err != nil {
+				What(log.Warn().Err(err).Str("exchange", e.GetName()).Str("pair", p.String()),
+					"unable to get taker fee")
+			}
+		}()
+	}
+
+	return 0, false
+}
+
+// takerFeeRate asks an exchange for its taker fee as a fraction of the
+// notional.
+func takerFeeRate(ctx context.Context, e exchange.IBotExchange, p currency.Pair, price float64) (float64, error) {
+	if price <= 0 {
+		return 0, fmt.Errorf("%w: invalid price %v", ErrUnknownFee, price)
+	}
+
+	// nolint: exhaustivestruct
+	fee, err := e.GetFeeByType(ctx, &exchange.FeeBuilder{
+		FeeType:       exchange.CryptocurrencyTradeFee,
+		Pair:          p,
+		IsMaker:       false,
+		PurchasePrice: price,
+		Amount:        1,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrUnknownFee, err)
+	}
+
+	return fee / price, nil
+}
@@ -0,0 +1,253 @@
+package dola
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+	"go.uber.org/multierr"
+)
+
+// +-------------+
+// | Kill switch |
+// +-------------+
+
+var ErrHalted = errors.New("trading halted")
+
+type haltState struct {
+	mu     sync.RWMutex
+	halted bool
+	reason string
+}
+
+// Halt blocks all further order submissions and modifications with ErrHalted
+// and cancels all open orders, including conditional ones, on all exchanges and
+// enabled pairs.  Trading stays
+// halted until Resume is called, even if some cancellations fail.
+func (bot *Keep) Halt(ctx context.Context, reason string) error {
+	bot.halt.mu.Lock()
+	bot.halt.halted = true
+	bot.halt.reason = reason
+	bot.halt.mu.Unlock()
+
+	What(log.Warn().Str("reason", reason), "trading halted")
+	bot.ReportEvent(HaltMetric, reason)
+
+	// Conditional orders would be rejected anyway once triggered.
+	for _, c := range bot.ConditionalOrders() {
+		_ = bot.CancelConditional(c.Exchange, c.ID)
+	}
+
+	var wg ErrorWaitGroup
+
+	for _, e := range bot.GetExchanges() {
+		wg.Add(1)
+
+		go func(e exchange.IBotExchange) {
+			wg.Done(bot.cancelEverything(ctx, e))
+		}(e)
+	}
+
+	return wg.Wait()
+}
+
+// Resume lifts a previous Halt.
+func (bot *Keep) Resume() {
+	bot.halt.mu.Lock()
+	bot.halt.halted = false
+	bot.halt.reason = ""
+	bot.halt.mu.Unlock()
+
+	What(log.Warn(), "trading resumed")
+}
+
+// Halted reports whether trading is halted and why.
+func (bot *Keep) Halted() (bool, string) {
+	bot.halt.mu.RLock()
+	defer bot.halt.mu.RUnlock()
+
+	return bot.halt.halted, bot.halt.reason
+}
+
+func (bot *Keep) checkHalted() error {
+	if halted, reason := bot.Halted(); halted {
+		return fmt.Errorf("%w: %s", ErrHalted, reason)
+	}
+
+	return nil
+}
+
+// throttleTrading waits for the exchange's rate limits like throttle and then
+// checks again whether trading is halted, as submissions and modifications
+// queued up behind Halt's cancellations must not reach the exchange.
+func (bot *Keep) throttleTrading(ctx context.Context, e exchange.IBotExchange, t RequestType) error {
+	if err := bot.throttle(ctx, e, t); err != nil {
+		return err
+	}
+
+	return bot.checkHalted()
+}
+
+// cancelEverything cancels all open orders on all enabled pairs of an exchange.
+// CancelAllOrders is tried first and, if it fails, orders are cancelled one by
+// one.
+func (bot *Keep) cancelEverything(ctx context.Context, e exchange.IBotExchange) error {
+	var multi error
+
+	for _, a := range e.GetAssetTypes(true) {
+		pairs, err := e.GetEnabledPairs(a)
+		if err != nil {
+			multi = multierr.Append(multi, err)
+
+			continue
+		}
+
+		for _, p := range pairs {
+			if _, err := bot.CancelAllOrders(ctx, e, a, p); err == nil {
+				continue
+			}
+
+			// nolint: exhaustivestruct
+			multi = multierr.Append(multi, bot.CancelOrdersByPrefix(ctx, e, order.Cancel{
+				Type:      order.AnyType,
+				Side:      order.AnySide,
+				AssetType: a,
+				Pair:      p,
+			}, ""))
+		}
+	}
+
+	if multi != nil {
+		What(log.Error().Err(multi).Str("exchange", e.GetName()), "unable to cancel all orders")
+	}
+
+	return multi
+}
+
+// +----------------------+
+// | Kill switch triggers |
+// +----------------------+
+
+// HaltOnSignal halts trading whenever one of the given signals is received,
+// until ctx is done.
+func (bot *Keep) HaltOnSignal(ctx context.Context, sigs ...os.Signal) {
+	if len(sigs) == 0 {
+		panic("invalid argument")
+	}
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+
+	go func() {
+		CheckerPush()
+
+		defer CheckerPop()
+		defer signal.Stop(ch)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case sig := <-ch:
+				_ = bot.Halt(ctx, fmt.Sprintf("received signal %s", sig))
+			}
+		}
+	}()
+}
+
+// HaltOnFile halts trading whenever a file at path exists, checking every
+// interval until ctx is done.  Trading is halted again after Resume if the
+// file still exists.
+func (bot *Keep) HaltOnFile(ctx context.Context, path string, interval time.Duration) {
+	path = ExpandUser(path)
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		CheckerPush()
+
+		defer CheckerPop()
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if halted, _ := bot.Halted(); !halted && FileExists(path) {
+					_ = bot.Halt(ctx, fmt.Sprintf("sentinel file %s exists", path))
+				}
+			}
+		}
+	}()
+}
+
+// errorRateHalter is a Reporter that halts trading once more than limit error
+// metrics are reported within window.
+type errorRateHalter struct {
+	keep   *Keep
+	limit  int
+	window time.Duration
+
+	mu     sync.Mutex
+	errors []time.Time
+}
+
+func newErrorRateHalter(k *Keep, limit int, window time.Duration) *errorRateHalter {
+	return &errorRateHalter{
+		keep:   k,
+		limit:  limit,
+		window: window,
+		mu:     sync.Mutex{},
+		errors: []time.Time{},
+	}
+}
+
+func (h *errorRateHalter) Event(m Metric, labels ...string) {
+	switch m { // nolint: exhaustive
+	case SubmitOrderErrorMetric,
+		ModifyOrderErrorMetric,
+		CancelOrderErrorMetric,
+		CancelAllOrdersErrorMetric,
+		GetActiveOrdersErrorMetric:
+	default:
+		return
+	}
+
+	now := time.Now()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.errors = append(h.errors, now)
+	for len(h.errors) > 0 && now.Sub(h.errors[0]) > h.window {
+		h.errors = h.errors[1:]
+	}
+
+	if len(h.errors) <= h.limit {
+		return
+	}
+
+	if halted, _ := h.keep.Halted(); halted {
+		return
+	}
+
+	h.errors = h.errors[:0]
+	reason := fmt.Sprintf("more than %d errors within %s", h.limit, h.window)
+
+	// Halt in the background as the error being reported may come from within
+	// an order call, and Halt itself reports errors.
+	go func() {
+		_ = h.keep.Halt(context.Background(), reason)
+	}()
+}
+
+func (h *errorRateHalter) Latency(m Metric, d time.Duration, labels ...string) {}
+
+func (h *errorRateHalter) Value(m Metric, v float64, labels ...string) {}
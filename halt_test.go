@@ -0,0 +1,121 @@
+package dola_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/numeusxyz/dola"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	"github.com/thrasher-corp/gocryptotrader/engine"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+)
+
+func TestKeep_Halt(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k   dola.Keep
+		e   = newFakeExchange("fake")
+		ctx = context.Background()
+	)
+
+	if err := k.Halt(ctx, "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	if halted, reason := k.Halted(); !halted || reason != "test" {
+		t.Errorf("have (%v, %q), want (true, \"test\")", halted, reason)
+	}
+
+	if _, err := k.SubmitOrder(ctx, e, order.Submit{}); !errors.Is(err, dola.ErrHalted) { // nolint: exhaustivestruct
+		t.Errorf("have %v, want %v", err, dola.ErrHalted)
+	}
+
+	if _, err := k.ModifyOrder(ctx, e, order.Modify{}); !errors.Is(err, dola.ErrHalted) { // nolint: exhaustivestruct
+		t.Errorf("have %v, want %v", err, dola.ErrHalted)
+	}
+
+	k.Resume()
+
+	if _, err := k.SubmitOrder(ctx, e, order.Submit{}); err != nil { // nolint: exhaustivestruct
+		t.Error(err)
+	}
+}
+
+// nolint: exhaustivestruct
+func TestKeep_Halt_CancelsEverything(t *testing.T) {
+	t.Parallel()
+
+	pair := currency.NewPair(currency.BTC, currency.USDT)
+
+	for _, cancelAllErr := range []error{nil, errors.New("not supported")} {
+		var (
+			k = dola.Keep{ExchangeManager: *engine.SetupExchangeManager()}
+			e = newFakeExchange("fake")
+		)
+
+		e.pairs = currency.Pairs{pair}
+		e.cancelAllErr = cancelAllErr
+		e.active = []order.Detail{
+			{ID: "1", Pair: pair, AssetType: asset.Spot},
+			{ID: "2", Pair: pair, AssetType: asset.Spot},
+		}
+
+		k.ExchangeManager.Add(e)
+
+		if err := k.Halt(context.Background(), "test"); err != nil {
+			t.Fatal(err)
+		}
+
+		all, each := e.allCancellations(), e.cancellations()
+
+		if cancelAllErr == nil {
+			if len(all) != 1 || !all[0].Pair.Equal(pair) || len(each) != 0 {
+				t.Errorf("unexpected cancellations: %+v, %+v", all, each)
+			}
+		} else if len(all) != 0 || len(each) != 2 || each[0].ID != "1" || each[1].ID != "2" {
+			t.Errorf("unexpected fallback cancellations: %+v, %+v", all, each)
+		}
+	}
+}
+
+// nolint: exhaustivestruct
+func TestKeep_Halt_Throttled(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k    dola.Keep
+		e    = newFakeExchange("fake")
+		ctx  = context.Background()
+		done = make(chan error)
+	)
+
+	k.SetRateLimits(e.GetName(), dola.RateLimits{Submit: dola.Rate{PerSecond: 5, Burst: 1}})
+
+	// Spend the only token so that the next submission has to wait.
+	if _, err := k.SubmitOrder(ctx, e, order.Submit{}); err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		_, err := k.SubmitOrder(ctx, e, order.Submit{})
+		done <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+
+	if err := k.Halt(ctx, "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-done; !errors.Is(err, dola.ErrHalted) {
+		t.Errorf("have %v, want %v", err, dola.ErrHalted)
+	}
+
+	if n := len(e.submissions()); n != 1 {
+		t.Errorf("have %d submissions, want 1", n)
+	}
+}
@@ -0,0 +1,353 @@
+package dola
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+)
+
+// +--------------+
+// | IcebergOrder |
+// +--------------+
+
+var (
+	ErrIcebergNotFound = errors.New("iceberg order not found")
+	ErrInvalidIceberg  = errors.New("iceberg order needs a limit price and a visible amount")
+)
+
+// IcebergOrder is a large limit order of which only a slice of about Visible is
+// shown at a time.  Each slice is placed once the previous one fills.
+type IcebergOrder struct {
+	Submit  order.Submit
+	Visible float64
+	// SizeVariance randomises the size of each slice by up to
+	// SizeVariance*Visible in either direction.
+	SizeVariance float64
+	// PriceVariance randomises the price of each slice by up to PriceVariance
+	// away from Submit.Price, i.e. never crossing it.
+	PriceVariance float64
+}
+
+// iceberg tracks a submitted IcebergOrder.  It is the UserData of all its
+// slices, while the parent (and the caller's UserData) lives in the registry as
+// a synthetic order.
+type iceberg struct {
+	IcebergOrder
+	id       string
+	exchange exchange.IBotExchange
+
+	mu         sync.Mutex
+	detail     order.Detail
+	cost       float64
+	slices     map[string]*icebergSlice
+	live       string
+	cancelling bool
+	finished   bool
+}
+
+type icebergSlice struct {
+	executed float64
+	closed   bool
+}
+
+// SubmitIceberg places the first slice of an iceberg order and returns the ID
+// of the parent.  The parent is kept in the registry with aggregate fill state
+// and its updates are delivered to userData like those of any other order, from
+// the exchange's event loop (see Loop).
+func (bot *Keep) SubmitIceberg(ctx context.Context,
+	exchangeOrName interface{},
+	x IcebergOrder,
+	userData interface{}) (string, error) {
+	e := bot.getExchange(exchangeOrName)
+
+	if x.Submit.Price <= 0 || x.Visible <= 0 {
+		return "", ErrInvalidIceberg
+	}
+
+	if err := x.Submit.Validate(); err != nil {
+		return "", err
+	}
+
+	x.Submit.Exchange = e.GetName()
+	x.Submit.Type = order.Limit
+
+	ice := &iceberg{ // nolint: exhaustivestruct
+		IcebergOrder: x,
+		id:           RandomOrderID("ice-"),
+		exchange:     e,
+		slices:       make(map[string]*icebergSlice),
+	}
+	ice.detail = order.Detail{ // nolint: exhaustivestruct
+		Price:           x.Submit.Price,
+		Amount:          x.Submit.Amount,
+		RemainingAmount: x.Submit.Amount,
+		Exchange:        e.GetName(),
+		ID:              ice.id,
+		ClientOrderID:   x.Submit.ClientOrderID,
+		Type:            order.Limit,
+		Side:            x.Submit.Side,
+		Status:          order.New,
+		AssetType:       x.Submit.AssetType,
+		Date:            time.Now(),
+		LastUpdated:     time.Now(),
+		Pair:            x.Submit.Pair,
+	}
+
+	if !bot.registry.StoreValue(e.GetName(), OrderValue{
+		Submit:         x.Submit,
+		SubmitResponse: order.SubmitResponse{OrderID: ice.id}, // nolint: exhaustivestruct
+		UserData:       userData,
+		Detail:         order.Detail{}, // nolint: exhaustivestruct
+		Synthetic:      true,
+	}) {
+		return "", ErrOrdersAlreadyExists
+	}
+
+	bot.icebergs.Store(ice.id, ice)
+	ice.emit(bot, order.New)
+
+	if err := ice.place(ctx, bot); err != nil {
+		ice.finish(bot, order.Rejected)
+
+		return ice.id, err
+	}
+
+	return ice.id, nil
+}
+
+// CancelIceberg cancels the visible slice of an iceberg order and stops placing
+// new ones.
+func (bot *Keep) CancelIceberg(ctx context.Context, id string) error {
+	x, ok := bot.icebergs.Load(id)
+	if !ok {
+		return ErrIcebergNotFound
+	}
+
+	ice, _ := x.(*iceberg)
+
+	ice.mu.Lock()
+	ice.cancelling = true
+	live := ice.live
+	ice.mu.Unlock()
+
+	if live == "" {
+		ice.mu.Lock()
+		status := cancelledStatus(ice.detail)
+		ice.mu.Unlock()
+
+		ice.finish(bot, status)
+
+		return nil
+	}
+
+	// nolint: exhaustivestruct
+	return bot.CancelOrder(ctx, ice.exchange, order.Cancel{
+		ID:        live,
+		Side:      ice.Submit.Side,
+		AssetType: ice.Submit.AssetType,
+		Pair:      ice.Submit.Pair,
+	})
+}
+
+// place submits the next slice, unless the iceberg is being cancelled.  A
+// slice submitted while CancelIceberg runs gets cancelled right away.
+func (ice *iceberg) place(ctx context.Context, k *Keep) error {
+	ice.mu.Lock()
+	if ice.cancelling {
+		ice.mu.Unlock()
+
+		return nil
+	}
+
+	s := ice.Submit
+	s.Amount = ice.sliceAmount(ice.detail.RemainingAmount)
+	s.Price = ice.slicePrice()
+	s.ClientOrderID = ""
+	ice.mu.Unlock()
+
+	resp, err := k.SubmitOrderUD(ctx, ice.exchange, s, ice)
+	if err != nil {
+		return err
+	}
+
+	// The slice may have been closed before SubmitOrderUD returned, e.g. in
+	// dry-run mode.
+	ice.mu.Lock()
+	_, closed := ice.slices[resp.OrderID]
+	if !closed {
+		ice.slices[resp.OrderID] = &icebergSlice{executed: 0, closed: false}
+		ice.live = resp.OrderID
+	}
+	cancelling := ice.cancelling
+	ice.mu.Unlock()
+
+	if !cancelling || closed {
+		return nil
+	}
+
+	// nolint: exhaustivestruct
+	if err := k.CancelOrder(ctx, ice.exchange, order.Cancel{
+		ID:        resp.OrderID,
+		Side:      s.Side,
+		AssetType: s.AssetType,
+		Pair:      s.Pair,
+	}); err != nil {
+		What(log.Error().Err(err).Str("exchange", ice.exchange.GetName()).Str("id", ice.id),
+			"unable to cancel iceberg slice placed while cancelling")
+	}
+
+	return nil
+}
+
+func (ice *iceberg) sliceAmount(remaining float64) float64 {
+	amount := ice.Visible
+	if ice.SizeVariance > 0 {
+		amount += ice.Visible * ice.SizeVariance * (2*rand.Float64() - 1) // nolint: gosec
+	}
+
+	// Don't leave a remainder too small to show.
+	if amount <= 0 || remaining-amount < ice.Visible*(1-ice.SizeVariance)/2 {
+		amount = remaining
+	}
+
+	return math.Min(amount, remaining)
+}
+
+func (ice *iceberg) slicePrice() float64 {
+	if ice.PriceVariance <= 0 {
+		return ice.Submit.Price
+	}
+
+	offset := ice.PriceVariance * rand.Float64() // nolint: gosec
+	if isBuy(ice.Submit.Side) {
+		return ice.Submit.Price - offset
+	}
+
+	return ice.Submit.Price + offset
+}
+
+// update accounts for a slice update and returns the aggregate detail.
+func (ice *iceberg) update(x order.Detail, closed bool) order.Detail {
+	ice.mu.Lock()
+	defer ice.mu.Unlock()
+
+	slice, ok := ice.slices[x.ID]
+	if !ok {
+		slice = &icebergSlice{executed: 0, closed: false}
+		ice.slices[x.ID] = slice
+	}
+
+	if executed := executedAmount(x); executed > slice.executed {
+		price := x.AverageExecutedPrice
+		if price == 0 {
+			price = x.Price
+		}
+
+		ice.cost += (executed - slice.executed) * price
+		ice.detail.ExecutedAmount += executed - slice.executed
+		ice.detail.RemainingAmount = ice.detail.Amount - ice.detail.ExecutedAmount
+		ice.detail.AverageExecutedPrice = ice.cost / ice.detail.ExecutedAmount
+		ice.detail.Status = order.PartiallyFilled
+		slice.executed = executed
+	}
+
+	if closed {
+		slice.closed = true
+
+		if ice.live == x.ID {
+			ice.live = ""
+		}
+	}
+
+	ice.detail.LastUpdated = time.Now()
+
+	return ice.detail
+}
+
+func (ice *iceberg) emit(k *Keep, status order.Status) {
+	ice.mu.Lock()
+	ice.detail.Status = status
+	x := ice.detail
+	ice.mu.Unlock()
+
+	k.queueOrder(ice.exchange, x)
+}
+
+// finish emits the final update of the parent, once.
+func (ice *iceberg) finish(k *Keep, status order.Status) {
+	ice.mu.Lock()
+	finished := ice.finished
+	ice.finished = true
+	ice.mu.Unlock()
+
+	if finished {
+		return
+	}
+
+	k.icebergs.Delete(ice.id)
+	ice.emit(k, status)
+}
+
+// +-----------------------+
+// | iceberg: observations |
+// +-----------------------+
+
+func (ice *iceberg) OnPartiallyFilled(k *Keep, e exchange.IBotExchange, x order.Detail, delta float64) {
+	detail := ice.update(x, false)
+
+	// A slice placed while cancelling may fill after the parent finished.
+	ice.mu.Lock()
+	finished := ice.finished
+	ice.mu.Unlock()
+
+	if !finished {
+		k.queueOrder(e, detail)
+	}
+}
+
+// OnFilled places the next slice, unless the iceberg is done.
+func (ice *iceberg) OnFilled(k *Keep, e exchange.IBotExchange, x order.Detail) {
+	detail := ice.update(x, true)
+
+	ice.mu.Lock()
+	cancelling := ice.cancelling
+	ice.mu.Unlock()
+
+	switch {
+	case detail.RemainingAmount <= 0:
+		ice.finish(k, order.Filled)
+	case cancelling:
+		ice.finish(k, cancelledStatus(detail))
+	default:
+		k.queueOrder(e, detail)
+
+		go func() {
+			if err := ice.place(context.Background(), k); err != nil {
+				What(log.Error().Err(err).Str("exchange", e.GetName()).Str("id", ice.id),
+					"unable to place next iceberg slice")
+				ice.finish(k, cancelledStatus(detail))
+			}
+		}()
+	}
+}
+
+func (ice *iceberg) OnCancelled(k *Keep, e exchange.IBotExchange, x order.Detail) {
+	ice.finish(k, cancelledStatus(ice.update(x, true)))
+}
+
+func (ice *iceberg) OnRejected(k *Keep, e exchange.IBotExchange, x order.Detail) {
+	ice.update(x, true)
+	ice.finish(k, x.Status)
+}
+
+func (ice *iceberg) OnExpired(k *Keep, e exchange.IBotExchange, x order.Detail) {
+	ice.update(x, true)
+	ice.finish(k, order.Expired)
+}
@@ -0,0 +1,148 @@
+package dola_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/numeusxyz/dola"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+)
+
+// nolint: exhaustivestruct
+func TestKeep_SubmitIceberg(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k        dola.Keep
+		e        = newFakeExchange("fake")
+		pair     = currency.NewPair(currency.BTC, currency.USDT)
+		statuses []order.Status
+		record   = func(k *dola.Keep, e exchange.IBotExchange, x order.Detail) {
+			statuses = append(statuses, x.Status)
+		}
+	)
+
+	id, err := k.SubmitIceberg(context.Background(), e, dola.IcebergOrder{
+		Submit: order.Submit{
+			Amount:    2.5,
+			Price:     100,
+			Type:      order.Limit,
+			Side:      order.Buy,
+			Pair:      pair,
+			AssetType: asset.Spot,
+		},
+		Visible:       1,
+		PriceVariance: 0.5,
+	}, &dola.Slots{OnFilledSlot: record})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	xs := fillSubmissions(t, &k, e, 3, 99.75)
+
+	if len(xs) != 3 || xs[0].Amount != 1 || xs[1].Amount != 1 || xs[2].Amount != 0.5 {
+		t.Fatalf("unexpected slices: %+v", xs)
+	}
+
+	for _, x := range xs {
+		if x.Price > 100 || x.Price < 99.5 {
+			t.Errorf("slice price %v out of band", x.Price)
+		}
+	}
+
+	// Updates of the parent are delivered by the exchange's event loop only.
+	if len(statuses) != 0 {
+		t.Errorf("have %d updates delivered outside the event loop, want none", len(statuses))
+	}
+
+	k.DeliverQueuedOrders(e)
+
+	value, ok := k.GetOrderValue(e.GetName(), id)
+	if !ok || !value.Synthetic {
+		t.Fatalf("unexpected registry value: %+v", value)
+	}
+
+	if value.Detail.Status != order.Filled || value.Detail.ExecutedAmount != 2.5 ||
+		value.Detail.AverageExecutedPrice != 99.75 {
+		t.Errorf("unexpected aggregate detail: %+v", value.Detail)
+	}
+
+	if len(statuses) != 1 || statuses[0] != order.Filled {
+		t.Errorf("unexpected filled notifications: %v", statuses)
+	}
+
+	if err := k.CancelIceberg(context.Background(), id); err == nil {
+		t.Error("filled iceberg can still be cancelled")
+	}
+}
+
+// nolint: exhaustivestruct
+func TestKeep_CancelIceberg_BetweenSlices(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k          dola.Keep
+		e          = newFakeExchange("fake")
+		pair       = currency.NewPair(currency.BTC, currency.USDT)
+		submitting = make(chan struct{})
+		proceed    = make(chan struct{})
+	)
+
+	// Hold the second slice until the iceberg is cancelled.
+	e.reject = func(x order.Submit) error {
+		if e.counter == 1 {
+			close(submitting)
+			<-proceed
+		}
+
+		return nil
+	}
+
+	id, err := k.SubmitIceberg(context.Background(), e, dola.IcebergOrder{
+		Submit: order.Submit{
+			Amount:    2,
+			Price:     100,
+			Type:      order.Limit,
+			Side:      order.Buy,
+			Pair:      pair,
+			AssetType: asset.Spot,
+		},
+		Visible: 1,
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k.OnOrder(e, order.Detail{
+		ID:             "1",
+		Amount:         1,
+		ExecutedAmount: 1,
+		Price:          100,
+		Status:         order.Filled,
+		Pair:           pair,
+		AssetType:      asset.Spot,
+	})
+
+	<-submitting
+
+	if err := k.CancelIceberg(context.Background(), id); err != nil {
+		t.Fatal(err)
+	}
+
+	k.DeliverQueuedOrders(e)
+
+	value, _ := k.GetOrderValue(e.GetName(), id)
+	if value.Detail.Status != order.PartiallyCancelled {
+		t.Errorf("have status %s, want %s", value.Detail.Status, order.PartiallyCancelled)
+	}
+
+	close(proceed)
+	waitFor(func() bool { return len(e.cancellations()) > 0 })
+
+	if xs := e.cancellations(); len(xs) != 1 || xs[0].ID != "2" {
+		t.Errorf("have cancellations %+v, want the second slice's", xs)
+	}
+}
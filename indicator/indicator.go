@@ -0,0 +1,188 @@
+// Package indicator provides streaming technical indicators.  Each update takes
+// amortised O(1) time, so indicators can be fed from a historian callback on
+// every tick instead of recomputing the whole window.
+package indicator
+
+import (
+	"math"
+
+	"github.com/numeusxyz/dola"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/trade"
+)
+
+// Indicator is a streaming indicator over a series of values.
+type Indicator interface {
+	// Update feeds the next value of the series and returns the new value of
+	// the indicator.
+	Update(x float64) float64
+	// Value returns the latest value of the indicator.
+	Value() float64
+	// Ready reports whether enough values have been fed for Value to be
+	// meaningful.
+	Ready() bool
+}
+
+// Feed returns a dola.TypedHistorian callback feeding the latest value of the
+// state to each of the indicators.
+func Feed(xs ...Indicator) func(*dola.Ring[float64]) {
+	return func(state *dola.Ring[float64]) {
+		x := state.Last()
+		for _, ind := range xs {
+			ind.Update(x)
+		}
+	}
+}
+
+// FeedArray is like Feed, but returns a dola.Historian callback.
+func FeedArray(xs ...Indicator) func(dola.Array) {
+	return func(state dola.Array) {
+		x := state.LastFloat()
+		for _, ind := range xs {
+			ind.Update(x)
+		}
+	}
+}
+
+// Replay feeds all values of a, oldest first, to ind, e.g. to catch up with the
+// state of a historian.
+func Replay(ind Indicator, a dola.Array) {
+	for i := 0; i < a.Len(); i++ {
+		x, ok := a.At(i).(float64)
+		if !ok {
+			panic("cast failed")
+		}
+
+		ind.Update(x)
+	}
+}
+
+// BarIndicator is a streaming indicator over a series of bars, such as ATR.
+type BarIndicator interface {
+	// UpdateBar feeds the next bar of the series and returns the new value of
+	// the indicator.
+	UpdateBar(high, low, closing float64) float64
+	Value() float64
+	Ready() bool
+}
+
+// TradeIndicator is a streaming indicator over a series of trades, such as
+// VWAP.
+type TradeIndicator interface {
+	// UpdateTrade feeds the next trade of the series and returns the new value
+	// of the indicator.
+	UpdateTrade(price, volume float64) float64
+	Value() float64
+	Ready() bool
+}
+
+// FeedBars returns a dola.TypedHistorian callback feeding the latest bar of the
+// state to each of the indicators, e.g. of an AggregateOHLC historian.
+func FeedBars(xs ...BarIndicator) func(*dola.Ring[dola.Bar]) {
+	return func(state *dola.Ring[dola.Bar]) {
+		x := state.Last()
+		for _, ind := range xs {
+			ind.UpdateBar(x.High, x.Low, x.Close)
+		}
+	}
+}
+
+// FeedTrades returns a dola.TypedHistorian callback feeding the latest trade of
+// the state to each of the indicators, e.g. of an OnTrade historian.
+func FeedTrades(xs ...TradeIndicator) func(*dola.Ring[trade.Data]) {
+	return func(state *dola.Ring[trade.Data]) {
+		x := state.Last()
+		for _, ind := range xs {
+			ind.UpdateTrade(x.Price, x.Amount)
+		}
+	}
+}
+
+// window keeps the last n values, their running sum and the running sum of
+// their squared deviations from the mean, updated as in Welford's algorithm.
+// Both are recomputed from scratch every n values to stop rounding errors from
+// accumulating.
+type window struct {
+	xs  dola.Ring[float64]
+	sum float64
+	m2  float64
+	// pushes counts the values pushed since the last recomputation.
+	pushes int
+}
+
+func newWindow(n int) window {
+	if n <= 0 {
+		panic("invalid argument")
+	}
+
+	return window{
+		xs:     dola.NewRing[float64](n),
+		sum:    0,
+		m2:     0,
+		pushes: 0,
+	}
+}
+
+// push adds x and returns the value it evicted, if any.
+func (w *window) push(x float64) (evicted float64, ok bool) {
+	mean := w.mean()
+
+	if w.full() {
+		evicted, ok = w.xs.At(0), true
+		w.sum += x - evicted
+		w.m2 += (x - evicted) * (x - w.sum/float64(w.xs.Len()) + evicted - mean)
+	} else {
+		w.sum += x
+		w.m2 += (x - mean) * (x - w.sum/float64(w.xs.Len()+1))
+	}
+
+	w.xs.Push(x)
+
+	if w.pushes++; w.pushes >= w.xs.Cap() {
+		w.recompute()
+	}
+
+	return evicted, ok
+}
+
+func (w *window) recompute() {
+	w.sum, w.m2, w.pushes = 0, 0, 0
+
+	for i := 0; i < w.xs.Len(); i++ {
+		w.sum += w.xs.At(i)
+	}
+
+	mean := w.mean()
+
+	for i := 0; i < w.xs.Len(); i++ {
+		w.m2 += (w.xs.At(i) - mean) * (w.xs.At(i) - mean)
+	}
+}
+
+func (w *window) full() bool {
+	return w.xs.Len() == w.xs.Cap()
+}
+
+func (w *window) mean() float64 {
+	if w.xs.Len() == 0 {
+		return 0
+	}
+
+	return w.sum / float64(w.xs.Len())
+}
+
+// variance returns the population variance of the window.
+func (w *window) variance() float64 {
+	if w.xs.Len() == 0 {
+		return 0
+	}
+
+	// Guard against rounding errors.
+	return math.Max(w.m2/float64(w.xs.Len()), 0)
+}
+
+// meanSquare returns the mean of the squared values of the window.
+func (w *window) meanSquare() float64 {
+	mean := w.mean()
+
+	return w.variance() + mean*mean
+}
@@ -0,0 +1,114 @@
+package indicator_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/numeusxyz/dola"
+	"github.com/numeusxyz/dola/indicator"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/trade"
+)
+
+// series is a deterministic, wiggly price series.
+func series(n int) []float64 {
+	xs := make([]float64, n)
+	for i := range xs {
+		xs[i] = 100 + 10*math.Sin(float64(i)/3) + float64(i%7)
+	}
+
+	return xs
+}
+
+func near(t *testing.T, have, want float64) {
+	t.Helper()
+
+	if math.Abs(have-want) > 1e-9*math.Max(1, math.Abs(want)) {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
+
+func TestFeed(t *testing.T) {
+	t.Parallel()
+
+	var (
+		sma = indicator.NewSMA(2)
+		ema = indicator.NewEMA(2)
+		u   = dola.NewTypedHistorian(0, 10, indicator.Feed(sma, ema))
+	)
+
+	for _, x := range []float64{1, 2, 3} {
+		u.Update(time.Time{}, x)
+	}
+
+	near(t, sma.Value(), 2.5)
+
+	if !ema.Ready() {
+		t.Error("have not ready, want ready")
+	}
+}
+
+func TestFeedArray(t *testing.T) {
+	t.Parallel()
+
+	var (
+		sma = indicator.NewSMA(3)
+		u   = dola.NewHistorian(0, 10, indicator.FeedArray(sma))
+	)
+
+	for _, x := range []float64{1, 2, 3, 4} {
+		u.Update(time.Time{}, x)
+	}
+
+	near(t, sma.Value(), 3)
+
+	// Replaying the state yields the same.
+	replayed := indicator.NewSMA(3)
+	a := dola.NewCircularArray(10)
+
+	for _, x := range u.Floats() {
+		a.Push(x)
+	}
+
+	indicator.Replay(replayed, &a)
+	near(t, replayed.Value(), sma.Value())
+}
+
+// nolint: exhaustivestruct
+func TestFeedBars(t *testing.T) {
+	t.Parallel()
+
+	var (
+		atr = indicator.NewATR(2)
+		u   = dola.NewTypedHistorian(0, 10, indicator.FeedBars(atr))
+	)
+
+	u.Update(time.Time{}, dola.Bar{High: 10, Low: 8, Close: 9})
+	u.Update(time.Time{}, dola.Bar{High: 12, Low: 11, Close: 11})
+
+	near(t, atr.Value(), 2.5)
+
+	if !atr.Ready() {
+		t.Error("have not ready, want ready")
+	}
+}
+
+// nolint: exhaustivestruct
+func TestFeedTrades(t *testing.T) {
+	t.Parallel()
+
+	var (
+		vwap = indicator.NewVWAP(2)
+		u    = dola.NewTypedHistorian(0, 10, indicator.FeedTrades(vwap))
+	)
+
+	for _, x := range []trade.Data{{Price: 10, Amount: 1}, {Price: 20, Amount: 3}, {Price: 30, Amount: 1}} {
+		u.Update(time.Time{}, x)
+	}
+
+	near(t, vwap.Value(), (20*3+30*1)/4.0)
+
+	if !vwap.Ready() {
+		t.Error("have not ready, want ready")
+	}
+}
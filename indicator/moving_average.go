@@ -0,0 +1,114 @@
+package indicator
+
+// +-----+
+// | SMA |
+// +-----+
+
+// SMA is the simple moving average of the last n values.
+type SMA struct {
+	w window
+}
+
+func NewSMA(n int) *SMA {
+	return &SMA{w: newWindow(n)}
+}
+
+func (s *SMA) Update(x float64) float64 {
+	s.w.push(x)
+
+	return s.Value()
+}
+
+func (s *SMA) Value() float64 {
+	return s.w.mean()
+}
+
+func (s *SMA) Ready() bool {
+	return s.w.full()
+}
+
+// +-----+
+// | EMA |
+// +-----+
+
+// EMA is the exponential moving average with a smoothing factor of 2/(n+1).  It
+// is seeded with the simple average of the first n values.
+type EMA struct {
+	n     int
+	alpha float64
+	count int
+	value float64
+}
+
+func NewEMA(n int) *EMA {
+	if n <= 0 {
+		panic("invalid argument")
+	}
+
+	return &EMA{
+		n:     n,
+		alpha: 2 / float64(n+1), // nolint: gomnd
+		count: 0,
+		value: 0,
+	}
+}
+
+func (e *EMA) Update(x float64) float64 {
+	if e.count < e.n {
+		e.count++
+		e.value += (x - e.value) / float64(e.count)
+	} else {
+		e.value += e.alpha * (x - e.value)
+	}
+
+	return e.value
+}
+
+func (e *EMA) Value() float64 {
+	return e.value
+}
+
+func (e *EMA) Ready() bool {
+	return e.count >= e.n
+}
+
+// +-----+
+// | WMA |
+// +-----+
+
+// WMA is the linearly weighted moving average of the last n values, the latest
+// one weighing n and the oldest one 1.
+type WMA struct {
+	w        window
+	weighted float64
+}
+
+func NewWMA(n int) *WMA {
+	return &WMA{w: newWindow(n), weighted: 0}
+}
+
+func (m *WMA) Update(x float64) float64 {
+	// Each value already in the window loses one unit of weight, unless the
+	// window is still filling up.
+	if m.w.full() {
+		m.weighted -= m.w.sum
+	}
+
+	m.w.push(x)
+	m.weighted += float64(m.w.xs.Len()) * x
+
+	return m.Value()
+}
+
+func (m *WMA) Value() float64 {
+	n := float64(m.w.xs.Len())
+	if n == 0 {
+		return 0
+	}
+
+	return m.weighted / (n * (n + 1) / 2) // nolint: gomnd
+}
+
+func (m *WMA) Ready() bool {
+	return m.w.full()
+}
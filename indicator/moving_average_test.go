@@ -0,0 +1,76 @@
+package indicator_test
+
+import (
+	"testing"
+
+	"github.com/numeusxyz/dola/indicator"
+)
+
+func TestSMA(t *testing.T) {
+	t.Parallel()
+
+	xs := series(50)
+	s := indicator.NewSMA(5)
+
+	for i, x := range xs {
+		s.Update(x)
+
+		if i < 4 {
+			if s.Ready() {
+				t.Errorf("%d: have ready, want not ready", i)
+			}
+
+			continue
+		}
+
+		var sum float64
+		for _, y := range xs[i-4 : i+1] {
+			sum += y
+		}
+
+		near(t, s.Value(), sum/5)
+	}
+}
+
+func TestEMA(t *testing.T) {
+	t.Parallel()
+
+	xs := series(50)
+	e := indicator.NewEMA(3)
+
+	for _, x := range xs {
+		e.Update(x)
+	}
+
+	// Seeded with the SMA of the first three, then smoothed with alpha = 0.5.
+	want := (xs[0] + xs[1] + xs[2]) / 3
+	for _, x := range xs[3:] {
+		want += 0.5 * (x - want)
+	}
+
+	near(t, e.Value(), want)
+}
+
+func TestWMA(t *testing.T) {
+	t.Parallel()
+
+	xs := series(50)
+	m := indicator.NewWMA(4)
+
+	for i, x := range xs {
+		m.Update(x)
+
+		if i < 3 {
+			continue
+		}
+
+		want := (1*xs[i-3] + 2*xs[i-2] + 3*xs[i-1] + 4*xs[i]) / 10
+		near(t, m.Value(), want)
+	}
+
+	// While filling up, the weights run up to the number of values.
+	m = indicator.NewWMA(4)
+	m.Update(1)
+	m.Update(4)
+	near(t, m.Value(), 3)
+}
@@ -0,0 +1,120 @@
+package indicator
+
+import "math"
+
+// +-----+
+// | RSI |
+// +-----+
+
+// RSI is Wilder's relative strength index over n periods, ranging from 0 to 100.
+type RSI struct {
+	n       int
+	count   int
+	prev    float64
+	avgGain float64
+	avgLoss float64
+}
+
+func NewRSI(n int) *RSI {
+	if n <= 0 {
+		panic("invalid argument")
+	}
+
+	return &RSI{n: n, count: 0, prev: 0, avgGain: 0, avgLoss: 0}
+}
+
+func (r *RSI) Update(x float64) float64 {
+	r.count++
+	if r.count == 1 {
+		r.prev = x
+
+		return r.Value()
+	}
+
+	change := x - r.prev
+	r.prev = x
+	gain, loss := math.Max(change, 0), math.Max(-change, 0)
+
+	// The first n changes are averaged, later ones smoothed.
+	if changes := r.count - 1; changes <= r.n {
+		r.avgGain += (gain - r.avgGain) / float64(changes)
+		r.avgLoss += (loss - r.avgLoss) / float64(changes)
+	} else {
+		r.avgGain = (r.avgGain*float64(r.n-1) + gain) / float64(r.n)
+		r.avgLoss = (r.avgLoss*float64(r.n-1) + loss) / float64(r.n)
+	}
+
+	return r.Value()
+}
+
+func (r *RSI) Value() float64 {
+	switch {
+	case r.count < 2: // nolint: gomnd
+		return 50 // nolint: gomnd
+	case r.avgLoss == 0 && r.avgGain == 0:
+		return 50 // nolint: gomnd
+	case r.avgLoss == 0:
+		return 100 // nolint: gomnd
+	}
+
+	return 100 - 100/(1+r.avgGain/r.avgLoss) // nolint: gomnd
+}
+
+func (r *RSI) Ready() bool {
+	return r.count > r.n
+}
+
+// +------+
+// | MACD |
+// +------+
+
+// MACD is the moving average convergence divergence: the difference between a
+// fast and a slow EMA, along with an EMA of that difference, the signal line.
+// Value returns the MACD line.
+type MACD struct {
+	fast   *EMA
+	slow   *EMA
+	signal *EMA
+}
+
+// NewMACD returns a MACD; the classic parameters are 12, 26 and 9.
+func NewMACD(fast, slow, signal int) *MACD {
+	if fast >= slow {
+		panic("invalid argument")
+	}
+
+	return &MACD{
+		fast:   NewEMA(fast),
+		slow:   NewEMA(slow),
+		signal: NewEMA(signal),
+	}
+}
+
+func (m *MACD) Update(x float64) float64 {
+	m.fast.Update(x)
+	m.slow.Update(x)
+
+	// The signal line only starts once the MACD line is meaningful.
+	if m.slow.Ready() {
+		m.signal.Update(m.Value())
+	}
+
+	return m.Value()
+}
+
+func (m *MACD) Value() float64 {
+	return m.fast.Value() - m.slow.Value()
+}
+
+func (m *MACD) Signal() float64 {
+	return m.signal.Value()
+}
+
+// Histogram returns the difference between the MACD and the signal lines.
+func (m *MACD) Histogram() float64 {
+	return m.Value() - m.Signal()
+}
+
+func (m *MACD) Ready() bool {
+	return m.signal.Ready()
+}
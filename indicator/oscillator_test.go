@@ -0,0 +1,79 @@
+package indicator_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/numeusxyz/dola/indicator"
+)
+
+func TestRSI(t *testing.T) {
+	t.Parallel()
+
+	xs := series(60)
+	r := indicator.NewRSI(14)
+
+	for _, x := range xs {
+		r.Update(x)
+	}
+
+	var gain, loss float64
+
+	for i := 1; i <= 14; i++ {
+		gain += math.Max(xs[i]-xs[i-1], 0) / 14
+		loss += math.Max(xs[i-1]-xs[i], 0) / 14
+	}
+
+	for i := 15; i < len(xs); i++ {
+		gain = (gain*13 + math.Max(xs[i]-xs[i-1], 0)) / 14
+		loss = (loss*13 + math.Max(xs[i-1]-xs[i], 0)) / 14
+	}
+
+	near(t, r.Value(), 100-100/(1+gain/loss))
+
+	// Monotonic series.
+	up := indicator.NewRSI(3)
+	for _, x := range []float64{1, 2, 3, 4} {
+		up.Update(x)
+	}
+
+	if !up.Ready() || up.Value() != 100 {
+		t.Errorf("have %v, want 100", up.Value())
+	}
+}
+
+func TestMACD(t *testing.T) {
+	t.Parallel()
+
+	var (
+		xs           = series(80)
+		m            = indicator.NewMACD(12, 26, 9)
+		fast, slow   = indicator.NewEMA(12), indicator.NewEMA(26)
+		signal       = indicator.NewEMA(9)
+		lastLine     float64
+		readyAtIndex = -1
+	)
+
+	for i, x := range xs {
+		m.Update(x)
+		fast.Update(x)
+		slow.Update(x)
+
+		if slow.Ready() {
+			lastLine = fast.Value() - slow.Value()
+			signal.Update(lastLine)
+		}
+
+		if m.Ready() && readyAtIndex < 0 {
+			readyAtIndex = i
+		}
+	}
+
+	near(t, m.Value(), lastLine)
+	near(t, m.Signal(), signal.Value())
+	near(t, m.Histogram(), lastLine-signal.Value())
+
+	if readyAtIndex != 25+8 {
+		t.Errorf("have ready at %d, want at %d", readyAtIndex, 25+8)
+	}
+}
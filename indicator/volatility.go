@@ -0,0 +1,197 @@
+package indicator
+
+import "math"
+
+// +--------+
+// | StdDev |
+// +--------+
+
+// StdDev is the rolling population standard deviation of the last n values.
+type StdDev struct {
+	w window
+}
+
+func NewStdDev(n int) *StdDev {
+	return &StdDev{w: newWindow(n)}
+}
+
+func (s *StdDev) Update(x float64) float64 {
+	s.w.push(x)
+
+	return s.Value()
+}
+
+func (s *StdDev) Value() float64 {
+	return math.Sqrt(s.w.variance())
+}
+
+func (s *StdDev) Mean() float64 {
+	return s.w.mean()
+}
+
+func (s *StdDev) Ready() bool {
+	return s.w.full()
+}
+
+// +--------+
+// | ZScore |
+// +--------+
+
+// ZScore is the number of standard deviations the latest value is away from the
+// mean of the last n values, itself included.
+type ZScore struct {
+	s    StdDev
+	last float64
+}
+
+func NewZScore(n int) *ZScore {
+	return &ZScore{s: StdDev{w: newWindow(n)}, last: 0}
+}
+
+func (z *ZScore) Update(x float64) float64 {
+	z.last = x
+	z.s.Update(x)
+
+	return z.Value()
+}
+
+func (z *ZScore) Value() float64 {
+	sd := z.s.Value()
+	if sd == 0 {
+		return 0
+	}
+
+	return (z.last - z.s.Mean()) / sd
+}
+
+func (z *ZScore) Ready() bool {
+	return z.s.Ready()
+}
+
+// +-----------------+
+// | Bollinger bands |
+// +-----------------+
+
+// Bollinger are the Bollinger bands: the simple moving average of the last n
+// values plus and minus k standard deviations.  Value returns the middle band.
+type Bollinger struct {
+	s StdDev
+	k float64
+}
+
+// NewBollinger returns Bollinger bands; the classic parameters are 20 and 2.
+func NewBollinger(n int, k float64) *Bollinger {
+	return &Bollinger{s: StdDev{w: newWindow(n)}, k: k}
+}
+
+func (b *Bollinger) Update(x float64) float64 {
+	b.s.Update(x)
+
+	return b.Value()
+}
+
+func (b *Bollinger) Value() float64 {
+	return b.s.Mean()
+}
+
+func (b *Bollinger) Upper() float64 {
+	return b.s.Mean() + b.k*b.s.Value()
+}
+
+func (b *Bollinger) Lower() float64 {
+	return b.s.Mean() - b.k*b.s.Value()
+}
+
+func (b *Bollinger) Ready() bool {
+	return b.s.Ready()
+}
+
+// +-----+
+// | ATR |
+// +-----+
+
+// ATR is Wilder's average true range over n bars.  Unlike most indicators, it is
+// a BarIndicator, fed whole bars rather than single values.  See FeedBars.
+type ATR struct {
+	n         int
+	count     int
+	prevClose float64
+	value     float64
+}
+
+func NewATR(n int) *ATR {
+	if n <= 0 {
+		panic("invalid argument")
+	}
+
+	return &ATR{n: n, count: 0, prevClose: 0, value: 0}
+}
+
+func (a *ATR) UpdateBar(high, low, closing float64) float64 {
+	tr := high - low
+	if a.count > 0 {
+		tr = math.Max(tr, math.Max(math.Abs(high-a.prevClose), math.Abs(low-a.prevClose)))
+	}
+
+	a.prevClose = closing
+	a.count++
+
+	// The first n true ranges are averaged, later ones smoothed.
+	if a.count <= a.n {
+		a.value += (tr - a.value) / float64(a.count)
+	} else {
+		a.value = (a.value*float64(a.n-1) + tr) / float64(a.n)
+	}
+
+	return a.value
+}
+
+func (a *ATR) Value() float64 {
+	return a.value
+}
+
+func (a *ATR) Ready() bool {
+	return a.count >= a.n
+}
+
+// +---------------------+
+// | Realised volatility |
+// +---------------------+
+
+// RealisedVolatility is the root mean square of the log returns between the
+// last n+1 values, scaled by the square root of periodsPerYear to annualise it.
+// A periodsPerYear of 1 leaves it per period.
+type RealisedVolatility struct {
+	w     window
+	scale float64
+	prev  float64
+	count int
+}
+
+func NewRealisedVolatility(n int, periodsPerYear float64) *RealisedVolatility {
+	return &RealisedVolatility{
+		w:     newWindow(n),
+		scale: math.Sqrt(periodsPerYear),
+		prev:  0,
+		count: 0,
+	}
+}
+
+func (v *RealisedVolatility) Update(x float64) float64 {
+	if v.count > 0 && v.prev > 0 && x > 0 {
+		v.w.push(math.Log(x / v.prev))
+	}
+
+	v.prev = x
+	v.count++
+
+	return v.Value()
+}
+
+func (v *RealisedVolatility) Value() float64 {
+	return math.Sqrt(v.w.meanSquare()) * v.scale
+}
+
+func (v *RealisedVolatility) Ready() bool {
+	return v.w.full()
+}
@@ -0,0 +1,107 @@
+package indicator_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/numeusxyz/dola/indicator"
+)
+
+func meanStd(xs []float64) (float64, float64) {
+	var mean, variance float64
+	for _, x := range xs {
+		mean += x / float64(len(xs))
+	}
+
+	for _, x := range xs {
+		variance += (x - mean) * (x - mean) / float64(len(xs))
+	}
+
+	return mean, math.Sqrt(variance)
+}
+
+func TestStdDev_ZScore_Bollinger(t *testing.T) {
+	t.Parallel()
+
+	var (
+		xs = series(50)
+		s  = indicator.NewStdDev(10)
+		z  = indicator.NewZScore(10)
+		b  = indicator.NewBollinger(10, 2)
+	)
+
+	for i, x := range xs {
+		s.Update(x)
+		z.Update(x)
+		b.Update(x)
+
+		if i < 9 {
+			continue
+		}
+
+		mean, sd := meanStd(xs[i-9 : i+1])
+		near(t, s.Value(), sd)
+		near(t, z.Value(), (x-mean)/sd)
+		near(t, b.Value(), mean)
+		near(t, b.Upper(), mean+2*sd)
+		near(t, b.Lower(), mean-2*sd)
+	}
+}
+
+// TestStdDev_Precision feeds a long series at a high price level, where running
+// sums of squares lose the variance to rounding errors.
+func TestStdDev_Precision(t *testing.T) {
+	t.Parallel()
+
+	var (
+		xs = series(100000)
+		s  = indicator.NewStdDev(20)
+	)
+
+	for i := range xs {
+		xs[i] += 1e5
+		s.Update(xs[i])
+	}
+
+	_, sd := meanStd(xs[len(xs)-20:])
+	near(t, s.Value(), sd)
+}
+
+func TestATR(t *testing.T) {
+	t.Parallel()
+
+	a := indicator.NewATR(2)
+	a.UpdateBar(10, 8, 9) // TR 2
+	a.UpdateBar(12, 11, 11)
+
+	// The second TR is 3 because of the gap from the previous close.
+	if !a.Ready() {
+		t.Error("have not ready, want ready")
+	}
+
+	near(t, a.Value(), 2.5)
+
+	a.UpdateBar(11, 10, 10) // TR 1
+	near(t, a.Value(), (2.5+1)/2)
+}
+
+func TestRealisedVolatility(t *testing.T) {
+	t.Parallel()
+
+	var (
+		xs = series(30)
+		v  = indicator.NewRealisedVolatility(5, 365)
+	)
+
+	for _, x := range xs {
+		v.Update(x)
+	}
+
+	var sum float64
+	for i := len(xs) - 5; i < len(xs); i++ {
+		r := math.Log(xs[i] / xs[i-1])
+		sum += r * r
+	}
+
+	near(t, v.Value(), math.Sqrt(sum/5)*math.Sqrt(365))
+}
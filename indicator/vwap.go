@@ -0,0 +1,66 @@
+package indicator
+
+import "github.com/numeusxyz/dola"
+
+// VWAP is the volume weighted average price of the last n trades, or of all of
+// them if n is 0.  Unlike most indicators, it is a TradeIndicator, fed prices
+// along with volumes.  See FeedTrades.
+type VWAP struct {
+	prices  dola.Ring[float64]
+	volumes dola.Ring[float64]
+	// notional is the running sum of price * volume.
+	notional float64
+	volume   float64
+}
+
+func NewVWAP(n int) *VWAP {
+	if n < 0 {
+		panic("invalid argument")
+	}
+
+	return &VWAP{
+		prices:   dola.NewRing[float64](n),
+		volumes:  dola.NewRing[float64](n),
+		notional: 0,
+		volume:   0,
+	}
+}
+
+func (v *VWAP) UpdateTrade(price, volume float64) float64 {
+	if v.prices.Cap() > 0 {
+		if v.prices.Len() == v.prices.Cap() {
+			v.notional -= v.prices.At(0) * v.volumes.At(0)
+			v.volume -= v.volumes.At(0)
+		}
+
+		v.prices.Push(price)
+		v.volumes.Push(volume)
+	}
+
+	v.notional += price * volume
+	v.volume += volume
+
+	return v.Value()
+}
+
+func (v *VWAP) Value() float64 {
+	if v.volume <= 0 {
+		return 0
+	}
+
+	return v.notional / v.volume
+}
+
+// Volume returns the volume the average is taken over.
+func (v *VWAP) Volume() float64 {
+	return v.volume
+}
+
+// Ready reports whether n trades have been fed, or any trade if n is 0.
+func (v *VWAP) Ready() bool {
+	if v.prices.Cap() > 0 {
+		return v.prices.Len() == v.prices.Cap()
+	}
+
+	return v.volume > 0
+}
@@ -0,0 +1,25 @@
+package indicator_test
+
+import (
+	"testing"
+
+	"github.com/numeusxyz/dola/indicator"
+)
+
+func TestVWAP(t *testing.T) {
+	t.Parallel()
+
+	rolling, total := indicator.NewVWAP(2), indicator.NewVWAP(0)
+
+	for _, x := range [][2]float64{{10, 1}, {20, 3}, {30, 1}} {
+		rolling.UpdateTrade(x[0], x[1])
+		total.UpdateTrade(x[0], x[1])
+	}
+
+	near(t, rolling.Value(), (20*3+30*1)/4.0)
+	near(t, total.Value(), (10+20*3+30)/5.0)
+
+	if total.Volume() != 5 {
+		t.Errorf("have %v, want 5", total.Volume())
+	}
+}
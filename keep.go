@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -14,6 +15,8 @@ import (
 	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
 	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
 	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/orderbook"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/ticker"
 	gctlog "github.com/thrasher-corp/gocryptotrader/log"
 	"go.uber.org/multierr"
 )
@@ -25,6 +28,10 @@ const (
 	constDefaultValidateCredentialsTimeout = time.Second * 5
 )
 
+// DefaultTickerStaleness is how long after being received a ticker is
+// considered stale, unless configured otherwise.
+const DefaultTickerStaleness = 10 * time.Second
+
 // +-------------+
 // | KeepBuilder |
 // +-------------+
@@ -39,6 +46,15 @@ type KeepBuilder struct {
 	factory             ExchangeFactory
 	settings            engine.Settings
 	reporters           []Reporter
+	risk                []RiskCheck
+	errorRateLimit      int
+	errorRateWindow     time.Duration
+	rateLimits          map[string]RateLimits
+	simulateFills       bool
+	conditionalsPath    string
+	retry               RetryPolicy
+	tickerStaleness     time.Duration
+	consolidation       *ConsolidationOptions
 }
 
 func NewKeepBuilder() *KeepBuilder {
@@ -50,6 +66,15 @@ func NewKeepBuilder() *KeepBuilder {
 		factory:             nil,
 		settings:            settings,
 		reporters:           []Reporter{},
+		risk:                []RiskCheck{},
+		errorRateLimit:      0,
+		errorRateWindow:     0,
+		rateLimits:          make(map[string]RateLimits),
+		simulateFills:       false,
+		conditionalsPath:    "",
+		retry:               RetryPolicy{Attempts: 0, Backoff: 0, IsTransient: nil},
+		tickerStaleness:     DefaultTickerStaleness,
+		consolidation:       nil,
 	}
 }
 
@@ -83,6 +108,70 @@ func (b *KeepBuilder) Reporter(r Reporter) *KeepBuilder {
 	return b
 }
 
+// RiskCheck adds a pre-trade check that runs, in the order added, before every
+// order submission and modification.
+func (b *KeepBuilder) RiskCheck(c RiskCheck) *KeepBuilder {
+	b.risk = append(b.risk, c)
+
+	return b
+}
+
+// HaltOnErrorRate halts trading once more than limit order errors are reported
+// within window.  See Keep.Halt.
+func (b *KeepBuilder) HaltOnErrorRate(limit int, window time.Duration) *KeepBuilder {
+	b.errorRateLimit = limit
+	b.errorRateWindow = window
+
+	return b
+}
+
+// RateLimits throttles order requests sent to an exchange.  See OrderScheduler.
+func (b *KeepBuilder) RateLimits(exchangeName string, l RateLimits) *KeepBuilder {
+	b.rateLimits[strings.ToLower(exchangeName)] = l
+
+	return b
+}
+
+// SimulateFills makes orders placed in dry-run mode (see
+// engine.Settings.EnableDryRun) fill against the latest order book.
+func (b *KeepBuilder) SimulateFills(enable bool) *KeepBuilder {
+	b.simulateFills = enable
+
+	return b
+}
+
+// ConditionalOrders persists conditional orders to a JSON file at path, so
+// that they survive restarts.  See Keep.SubmitConditional.
+func (b *KeepBuilder) ConditionalOrders(path string) *KeepBuilder {
+	b.conditionalsPath = path
+
+	return b
+}
+
+// TickerStaleness sets how long after being received a ticker is considered
+// stale.  See Keep.LastTicker.
+func (b *KeepBuilder) TickerStaleness(d time.Duration) *KeepBuilder {
+	b.tickerStaleness = d
+
+	return b
+}
+
+// RetrySubmissions resubmits orders that failed with a transient error.  See
+// RetryPolicy.
+func (b *KeepBuilder) RetrySubmissions(p RetryPolicy) *KeepBuilder {
+	b.retry = p
+
+	return b
+}
+
+// ConsolidateBooks merges the order books of each instrument across exchanges.
+// See Keep.ConsolidatedBook and ConsolidatedBookObserver.
+func (b *KeepBuilder) ConsolidateBooks(opts ConsolidationOptions) *KeepBuilder {
+	b.consolidation = &opts
+
+	return b
+}
+
 // nolint: funlen
 func (b *KeepBuilder) Build(ctx context.Context) (*Keep, error) {
 	// Resolve path to config file.
@@ -102,9 +191,33 @@ func (b *KeepBuilder) Build(ctx context.Context) (*Keep, error) {
 			Settings:        b.settings,
 			registry:        *NewOrderRegistry(),
 			reporters:       b.reporters,
+			risk:            b.risk,
+			tickers:         sync.Map{},
+			books:           sync.Map{},
+			halt:            haltState{}, // nolint: exhaustivestruct
+			schedulers:      make(map[string]*OrderScheduler),
+			paper:           paperTrader{simulateFills: b.simulateFills},           // nolint: exhaustivestruct
+			conditionals:    conditionalBook{path: ExpandUser(b.conditionalsPath)}, // nolint: exhaustivestruct
+			retry:           b.retry,
+			tickerStaleness: b.tickerStaleness,
+			consolidation:   b.consolidation,
 		}
 	)
 
+	// Restore conditional orders from a previous run.
+	if err := keep.loadConditionals(); err != nil {
+		return nil, err
+	}
+
+	for name, limits := range b.rateLimits {
+		keep.schedulers[name] = NewOrderScheduler(limits)
+	}
+
+	// Optionally halt trading on too many errors.
+	if b.errorRateWindow > 0 {
+		keep.reporters = append(keep.reporters, newErrorRateHalter(keep, b.errorRateLimit, b.errorRateWindow))
+	}
+
 	// Add history strategy: a special type of strategy that may keep multiple
 	// channels of historical data.
 	hist := NewHistoryStrategy()
@@ -161,6 +274,39 @@ type Keep struct {
 	Settings        engine.Settings
 	registry        OrderRegistry
 	reporters       []Reporter
+	risk            []RiskCheck
+	// tickers maps a marketKey to the last seen TickerSnapshot.
+	tickers sync.Map
+	// books maps a marketKey to the last seen BookSnapshot.
+	books sync.Map
+	halt  haltState
+	// schedulers maps a lower-cased exchange name to its rate limiter.  It is
+	// never modified after Build.
+	schedulers map[string]*OrderScheduler
+	// paper keeps the orders placed while in dry-run mode.
+	paper paperTrader
+	// updates queues the order updates emitted by Keep itself.
+	updates      orderQueues
+	conditionals conditionalBook
+	// openOrders holds the slots MaxOpenOrdersChecks reserved for orders being
+	// submitted.
+	openOrders openOrderReservations
+	// groups maps an OrderGroup's ID to the group.
+	groups sync.Map
+	// icebergs maps the ID of an active iceberg order to its state.
+	icebergs sync.Map
+	retry    RetryPolicy
+	// tickerStaleness defaults to DefaultTickerStaleness if zero.
+	tickerStaleness time.Duration
+	// consolidation is nil unless books are consolidated across exchanges.
+	consolidation *ConsolidationOptions
+	// consolidations maps a marketKey without exchange to its consolidation.
+	consolidations sync.Map
+	// consolidated maps a marketKey without exchange to the latest
+	// ConsolidatedBook.
+	consolidated sync.Map
+	// fees caches taker fee rates per instrument.
+	fees feeCache
 }
 
 // Run is the entry point of all exchange data streams.  Strategy.On*() events for a
@@ -210,13 +356,21 @@ func (bot *Keep) Run(ctx context.Context) {
 
 func Loop(ctx context.Context, k *Keep, e exchange.IBotExchange, s Strategy) error {
 	// If this exchange doesn't support websockets we still need to
-	// keep running
+	// keep running, if only to deliver the order updates Keep emits itself,
+	// e.g. those of dry runs and synthetic orders
 	if !e.IsWebsocketEnabled() {
 		What(log.Warn().Str("exchange", e.GetName()), "no websocket support")
 
-		<-ctx.Done()
+		updates := k.updates.get(e.GetName()).ready
 
-		return nil
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-updates:
+				k.deliverQueuedOrders(e, s)
+			}
+		}
 	}
 
 	// this exchanges does support websockets, go into an
@@ -224,16 +378,32 @@ func Loop(ctx context.Context, k *Keep, e exchange.IBotExchange, s Strategy) err
 	return Stream(ctx, k, e, s)
 }
 
-func (bot *Keep) AddHistorian(
-	exchangeName,
-	eventName string,
-	interval time.Duration,
-	stateLength int,
-	f func(Array),
-) error {
+func (bot *Keep) AddHistorian(c HistorianConfig) (*HistorianHandle, error) {
+	hist, err := bot.History()
+	if err != nil {
+		return nil, err
+	}
+
+	return hist.AddHistorian(c)
+}
+
+// Historian returns a copy of the state of the historian with the given name.
+// It is safe to call from any thread.
+func (bot *Keep) Historian(name string) (Array, error) {
+	hist, err := bot.History()
+	if err != nil {
+		return nil, err
+	}
+
+	return hist.Snapshot(name)
+}
+
+// History returns the strategy keeping historians, e.g. for use with
+// AddTypedHistorian.
+func (bot *Keep) History() (*HistoryStrategy, error) {
 	strategy, err := bot.Root.Get("history")
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	hist, ok := strategy.(*HistoryStrategy)
@@ -241,7 +411,7 @@ func (bot *Keep) AddHistorian(
 		panic("")
 	}
 
-	return hist.AddHistorian(exchangeName, eventName, interval, stateLength, f)
+	return hist, nil
 }
 
 func (bot *Keep) GetOrderValue(exchangeName, orderID string) (OrderValue, bool) {
@@ -264,10 +434,56 @@ func (bot *Keep) getExchange(x interface{}) exchange.IBotExchange {
 	}
 }
 
+// throttle waits until the exchange's rate limits allow a request of type t.
+func (bot *Keep) throttle(ctx context.Context, e exchange.IBotExchange, t RequestType) error {
+	s, ok := bot.schedulers[strings.ToLower(e.GetName())]
+	if !ok {
+		return nil
+	}
+
+	defer bot.ReportLatency(RateLimitLatencyMetric, time.Now(), e.GetName())
+
+	if err := s.Wait(ctx, t); err != nil {
+		bot.ReportEvent(RateLimitTimeoutMetric, e.GetName())
+
+		return err
+	}
+
+	return nil
+}
+
 // +----------------------+
 // | Keep: Exchange state |
 // +----------------------+
 
+func (bot *Keep) GetOrderInfo(ctx context.Context,
+	exchangeOrName interface{},
+	orderID string,
+	pair currency.Pair,
+	assetType asset.Item) (order.Detail, error) {
+	e := bot.getExchange(exchangeOrName)
+
+	bot.ReportEvent(GetOrderInfoMetric, e.GetName())
+
+	defer bot.ReportLatency(GetOrderInfoLatencyMetric, time.Now(), e.GetName())
+
+	x, err := bot.entry(e).GetOrderInfo(ctx, orderID, pair, assetType)
+	if err != nil {
+		bot.ReportEvent(GetOrderInfoErrorMetric, e.GetName())
+
+		return x, err
+	}
+
+	return x, nil
+}
+
+// OrderLineage returns the IDs of the orders orderID replaced (see
+// toolbelt.CancelReplace), starting with the original one and ending with
+// orderID.
+func (bot *Keep) OrderLineage(exchangeName, orderID string) []string {
+	return bot.registry.Lineage(exchangeName, orderID)
+}
+
 func (bot *Keep) GetActiveOrders(ctx context.Context, exchangeOrName interface{}, request order.GetOrdersRequest) (
 	[]order.Detail, error,
 ) {
@@ -279,7 +495,7 @@ func (bot *Keep) GetActiveOrders(ctx context.Context, exchangeOrName interface{}
 
 	defer bot.ReportLatency(GetActiveOrdersLatencyMetric, timer, e.GetName())
 
-	resp, err := e.GetActiveOrders(ctx, &request)
+	resp, err := bot.entry(e).GetActiveOrders(ctx, &request)
 	if err != nil {
 		bot.ReportEvent(GetActiveOrdersErrorMetric, e.GetName())
 
@@ -307,16 +523,29 @@ func (bot *Keep) SubmitOrderUD(ctx context.Context,
 ) {
 	e := bot.getExchange(exchangeOrName)
 
-	// Make sure order.Submit.Exchange is properly populated.
-	if submit.Exchange == "" {
-		submit.Exchange = e.GetName()
+	release, err := bot.preSubmit(e, &submit)
+	if err != nil {
+		return order.SubmitResponse{}, err // nolint: exhaustivestruct
+	}
+
+	defer release()
+
+	if err := bot.throttleTrading(ctx, e, SubmitRequest); err != nil {
+		return order.SubmitResponse{}, err // nolint: exhaustivestruct
 	}
 
 	bot.ReportEvent(SubmitOrderMetric, e.GetName())
 
 	defer bot.ReportLatency(SubmitOrderLatencyMetric, time.Now(), e.GetName())
 
-	resp, err := e.SubmitOrder(ctx, &submit)
+	entry := bot.entry(e)
+
+	// Retries need a stable client order ID to look the order up by.
+	if bot.retry.Attempts > 0 && submit.ClientOrderID == "" {
+		submit.ClientOrderID = RandomOrderID("")
+	}
+
+	resp, err := bot.submitWithRetries(ctx, e, entry, &submit)
 	if err != nil {
 		// post an error metric event
 		bot.ReportEvent(SubmitOrderErrorMetric, e.GetName())
@@ -324,31 +553,81 @@ func (bot *Keep) SubmitOrderUD(ctx context.Context,
 		return resp, err
 	}
 
-	// store the order in the registry
-	if !bot.registry.Store(e.GetName(), resp, userData) {
-		return resp, ErrOrdersAlreadyExists
+	return resp, bot.postSubmit(ctx, e, entry, submit, resp, userData)
+}
+
+// preSubmit populates submit and runs all checks an order has to pass before
+// being sent to the exchange.  The returned function has to be called once the
+// order is stored in the registry or its submission failed.
+func (bot *Keep) preSubmit(e exchange.IBotExchange, submit *order.Submit) (func(), error) {
+	if err := bot.checkHalted(); err != nil {
+		return func() {}, err
+	}
+
+	// Make sure order.Submit.Exchange is properly populated.
+	if submit.Exchange == "" {
+		submit.Exchange = e.GetName()
 	}
 
-	return resp, err
+	return bot.checkRisk(e, *submit)
 }
 
-func (bot *Keep) SubmitOrders(ctx context.Context, e exchange.IBotExchange, xs ...order.Submit) error {
-	var wg ErrorWaitGroup
+// postSubmit stores an order accepted by the exchange in the registry.
+func (bot *Keep) postSubmit(ctx context.Context,
+	e exchange.IBotExchange,
+	entry orderEntry,
+	submit order.Submit,
+	resp order.SubmitResponse,
+	userData interface{}) error {
+	// store the order in the registry
+	if !bot.registry.StoreValue(e.GetName(), OrderValue{
+		Submit:         submit,
+		SubmitResponse: resp,
+		UserData:       userData,
+		Detail:         order.Detail{}, // nolint: exhaustivestruct
+	}) {
+		return ErrOrdersAlreadyExists
+	}
 
+	// In dry-run mode order updates are emitted only once the order is stored,
+	// so that its observers get notified.
+	if p, ok := entry.(paperExchange); ok {
+		p.acknowledge(ctx, resp.OrderID)
+	}
+
+	return nil
+}
+
+// SubmitOrders submits xs and returns a result for each of them, in the same
+// order.  Exchanges implementing BatchSubmitter get all orders in a single
+// request, the rest get them concurrently, subject to opts.Concurrency.  The
+// returned error combines the errors of all results.
+func (bot *Keep) SubmitOrders(ctx context.Context,
+	e exchange.IBotExchange,
+	opts BatchOptions,
+	xs ...order.Submit) ([]SubmitResult, error) {
 	bot.ReportEvent(SubmitBulkOrderMetric, e.GetName())
 
 	defer bot.ReportLatency(SubmitBulkOrderLatencyMetric, time.Now(), e.GetName())
 
-	for _, x := range xs {
-		wg.Add(1)
+	results := make([]SubmitResult, len(xs))
+	for i, x := range xs {
+		results[i].Submit = x
+	}
 
-		go func(x order.Submit) {
-			_, err := bot.SubmitOrder(ctx, e, x)
-			wg.Done(err)
-		}(x)
+	if b, ok := bot.entry(e).(BatchSubmitter); ok {
+		bot.submitBatch(ctx, e, b, results)
+	} else {
+		bot.submitConcurrently(ctx, e, opts.Concurrency, results)
+	}
+
+	multi := combineResults(results)
+
+	if multi != nil && opts.AllOrNothing {
+		multi = multierr.Append(multi, bot.rollback(ctx, e, results))
 	}
 
-	return wg.Wait()
+	return results, multi
 }
 
 func (bot *Keep) ModifyOrder(ctx context.Context,
@@ -356,11 +635,26 @@ func (bot *Keep) ModifyOrder(ctx context.Context,
 	mod order.Modify) (order.Modify, error) {
 	e := bot.getExchange(exchangeOrName)
 
+	if err := bot.checkHalted(); err != nil {
+		return mod, err
+	}
+
+	release, err := bot.checkRisk(e, bot.modifyToSubmit(e, mod))
+	if err != nil {
+		return mod, err
+	}
+
+	defer release()
+
+	if err := bot.throttleTrading(ctx, e, ModifyRequest); err != nil {
+		return mod, err
+	}
+
 	bot.ReportEvent(ModifyOrderMetric, e.GetName())
 
 	defer bot.ReportLatency(ModifyOrderLatencyMetric, time.Now(), e.GetName())
 
-	resp, err := e.ModifyOrder(ctx, &mod)
+	resp, err := bot.entry(e).ModifyOrder(ctx, &mod)
 	if err != nil {
 		// post an error metric event
 		bot.ReportEvent(ModifyOrderErrorMetric, e.GetName())
@@ -371,6 +665,29 @@ func (bot *Keep) ModifyOrder(ctx context.Context,
 	return resp, nil
 }
 
+// modifyToSubmit returns the submission of the order being modified, updated
+// with the fields set in mod.  If the order isn't in the registry, it falls back
+// to ModifyToSubmit.
+func (bot *Keep) modifyToSubmit(e exchange.IBotExchange, mod order.Modify) order.Submit {
+	value, ok := bot.GetOrderValue(e.GetName(), mod.ID)
+	if !ok {
+		return ModifyToSubmit(mod)
+	}
+
+	submit := value.Submit
+	submit.ID = mod.ID
+
+	if mod.Price > 0 {
+		submit.Price = mod.Price
+	}
+
+	if mod.Amount > 0 {
+		submit.Amount = mod.Amount
+	}
+
+	return submit
+}
+
 // +--------------------------+
 // | Keep: Order cancellation |
 // +--------------------------+
@@ -389,11 +706,15 @@ func (bot *Keep) CancelAllOrders(ctx context.Context,
 	cancel.Pair = pair
 	cancel.Symbol = pair.String()
 
+	if err := bot.throttle(ctx, e, CancelRequest); err != nil {
+		return order.CancelAllResponse{}, err // nolint: exhaustivestruct
+	}
+
 	bot.ReportEvent(CancelAllOrdersMetric, e.GetName(), pair.String())
 
 	defer bot.ReportLatency(CancelAllOrdersLatencyMetric, time.Now(), e.GetName())
 
-	resp, err := e.CancelAllOrders(ctx, &cancel)
+	resp, err := bot.entry(e).CancelAllOrders(ctx, &cancel)
 	if err != nil {
 		bot.ReportEvent(CancelAllOrdersErrorMetric, e.GetName())
 
@@ -410,11 +731,15 @@ func (bot *Keep) CancelOrder(ctx context.Context, exchangeOrName interface{}, x
 		x.Exchange = e.GetName()
 	}
 
+	if err := bot.throttle(ctx, e, CancelRequest); err != nil {
+		return err
+	}
+
 	bot.ReportEvent(CancelOrderMetric, e.GetName())
 
 	defer bot.ReportLatency(CancelOrderLatencyMetric, time.Now(), e.GetName())
 
-	if err := e.CancelOrder(ctx, &x); err != nil {
+	if err := bot.entry(e).CancelOrder(ctx, &x); err != nil {
 		// post an error metric event
 		bot.ReportEvent(CancelOrderErrorMetric, e.GetName())
 
@@ -424,6 +749,9 @@ func (bot *Keep) CancelOrder(ctx context.Context, exchangeOrName interface{}, x
 	return nil
 }
 
+// CancelOrdersByPrefix cancels the active orders matching x's pair, asset, side
+// and type whose ClientOrderID starts with prefix (see RandomOrderID).  An empty
+// prefix matches all orders.
 func (bot *Keep) CancelOrdersByPrefix(ctx context.Context,
 	exchangeOrName interface{},
 	x order.Cancel,
@@ -438,58 +766,196 @@ func (bot *Keep) CancelOrdersByPrefix(ctx context.Context,
 		AssetType: x.AssetType,
 	}
 
-	xs, err := bot.GetActiveOrders(ctx, exchangeOrName, request)
+	_, err := bot.CancelOrdersWhere(ctx, exchangeOrName, request, func(x order.Detail) bool {
+		return strings.HasPrefix(x.ClientOrderID, prefix)
+	}, false)
+
+	return err
+}
+
+// CancelResult is the outcome of cancelling a single order.  Err is nil for
+// orders that got cancelled, as well as for all orders of a dry run.
+type CancelResult struct {
+	Order order.Detail
+	Err   error
+}
+
+// CancelOrdersWhere cancels the active orders matching request for which
+// predicate returns true.  If dryRun is set, nothing gets cancelled and the
+// results preview which orders would be.  The returned error combines the
+// errors of all results.
+func (bot *Keep) CancelOrdersWhere(ctx context.Context,
+	exchangeOrName interface{},
+	request order.GetOrdersRequest,
+	predicate func(order.Detail) bool,
+	dryRun bool) ([]CancelResult, error) {
+	e := bot.getExchange(exchangeOrName)
+
+	xs, err := bot.GetActiveOrders(ctx, e, request)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	var multi error
+	var (
+		results []CancelResult
+		multi   error
+	)
 
 	for _, x := range xs {
-		// Date is left empty on purpose just to make sure no matching by date is
-		// performed.  All the rest is populated as we don't really know which
-		// exchange expects what.
-		err := bot.CancelOrder(ctx, exchangeOrName, order.Cancel{
-			Price:         x.Price,
-			Amount:        x.Amount,
-			Exchange:      bot.getExchange(exchangeOrName).GetName(),
-			ID:            x.ID,
-			ClientOrderID: x.ClientOrderID,
-			AccountID:     x.AccountID,
-			ClientID:      x.ClientID,
-			WalletAddress: x.WalletAddress,
-			Type:          x.Type,
-			Side:          x.Side,
-			Status:        x.Status,
-			AssetType:     x.AssetType,
-			Date:          time.Time{},
-			Pair:          x.Pair,
-			Symbol:        x.Pair.String(),
-			Trades:        []order.TradeHistory{},
-		})
-
-		multi = multierr.Append(multi, err)
-	}
-
-	return multi
+		if !predicate(x) {
+			continue
+		}
+
+		result := CancelResult{Order: x, Err: nil}
+
+		if !dryRun {
+			// Date is left empty on purpose just to make sure no matching by date is
+			// performed.  All the rest is populated as we don't really know which
+			// exchange expects what.
+			result.Err = bot.CancelOrder(ctx, e, order.Cancel{
+				Price:         x.Price,
+				Amount:        x.Amount,
+				Exchange:      e.GetName(),
+				ID:            x.ID,
+				ClientOrderID: x.ClientOrderID,
+				AccountID:     x.AccountID,
+				ClientID:      x.ClientID,
+				WalletAddress: x.WalletAddress,
+				Type:          x.Type,
+				Side:          x.Side,
+				Status:        x.Status,
+				AssetType:     x.AssetType,
+				Date:          time.Time{},
+				Pair:          x.Pair,
+				Symbol:        x.Pair.String(),
+				Trades:        []order.TradeHistory{},
+			})
+			multi = multierr.Append(multi, result.Err)
+		}
+
+		results = append(results, result)
+	}
+
+	return results, multi
 }
 
 // +-------------------------+
 // | Keep: Event observation |
 // +-------------------------+
 
+// OnOrder tracks the lifecycle of orders submitted through Keep and notifies
+// the observers attached to them as UserData.  See notifyObservers.
 func (bot *Keep) OnOrder(e exchange.IBotExchange, x order.Detail) {
-	if x.Status == order.Filled {
-		value, ok := bot.GetOrderValue(e.GetName(), x.ID)
-		if !ok {
-			// No user data for this order.
-			return
-		}
+	prev, ok := bot.registry.Observe(e.GetName(), x)
+	if !ok {
+		// No user data for this order.
+		return
+	}
 
-		if obs, ok := value.UserData.(OnFilledObserver); ok {
-			obs.OnFilled(bot, e, x)
-		}
+	notifyObservers(bot, e, prev.UserData, prev.Detail, x)
+}
+
+// +-------------------+
+// | Keep: Market data |
+// +-------------------+
+
+// marketKey identifies a single instrument on an exchange.  Pair is formatted
+// with pairKey so that differently delimited pairs map to the same key.
+type marketKey struct {
+	Exchange string
+	Asset    asset.Item
+	Pair     string
+}
+
+func newMarketKey(exchangeName string, a asset.Item, p currency.Pair) marketKey {
+	return marketKey{
+		Exchange: strings.ToLower(exchangeName),
+		Asset:    a,
+		Pair:     pairKey(p),
+	}
+}
+
+func pairKey(p currency.Pair) string {
+	return p.Base.Upper().String() + "/" + p.Quote.Upper().String()
+}
+
+// OnPrice keeps track of the last seen price per instrument and triggers
+// conditional orders.
+func (bot *Keep) OnPrice(e exchange.IBotExchange, x ticker.Price) {
+	bot.tickers.Store(newMarketKey(e.GetName(), x.AssetType, x.Pair), TickerSnapshot{
+		Price:    x,
+		Received: time.Now(),
+		Stale:    false,
+	})
+
+	if price, ok := bot.lastPrice(e.GetName(), x.AssetType, x.Pair); ok {
+		bot.triggerConditionals(e, x.AssetType, x.Pair, func(order.Side) float64 { return price })
+	}
+}
+
+// lastPrice returns the last traded price of a pair, falling back to the
+// mid price if the exchange doesn't report it.
+func (bot *Keep) lastPrice(exchangeName string, a asset.Item, p currency.Pair) (float64, bool) {
+	x, ok := bot.LastTicker(exchangeName, a, p)
+
+	switch {
+	case !ok:
+		return 0, false
+	case x.Last > 0:
+		return x.Last, true
+	case x.Bid > 0 && x.Ask > 0:
+		return (x.Bid + x.Ask) / 2, true // nolint: gomnd
+	default:
+		return 0, false
+	}
+}
+
+// TickerSnapshot is the latest ticker seen for an instrument.  The exchange's
+// timestamp is Price.LastUpdated.
+type TickerSnapshot struct {
+	ticker.Price
+	// Received is when Keep received the ticker.
+	Received time.Time
+	// Stale is set if the ticker was received longer than the staleness
+	// threshold ago (see KeepBuilder.TickerStaleness).
+	Stale bool
+}
+
+// LastTicker returns the latest ticker streamed for an instrument.
+func (bot *Keep) LastTicker(exchangeName string, a asset.Item, p currency.Pair) (TickerSnapshot, bool) {
+	pointer, ok := bot.tickers.Load(newMarketKey(exchangeName, a, p))
+	if !ok {
+		return TickerSnapshot{}, false // nolint: exhaustivestruct
 	}
+
+	x, ok := pointer.(TickerSnapshot)
+	if !ok {
+		panic(fmt.Sprintf("have %T, want TickerSnapshot", pointer))
+	}
+
+	staleness := bot.tickerStaleness
+	if staleness == 0 {
+		staleness = DefaultTickerStaleness
+	}
+
+	x.Stale = time.Since(x.Received) > staleness
+
+	return x, true
+}
+
+// OnOrderBook caches the book (see OrderBook), consolidates it with the other
+// exchanges' (see ConsolidatedBook), matches the orders placed in dry-run mode
+// against it and triggers conditional orders.
+func (bot *Keep) OnOrderBook(e exchange.IBotExchange, x orderbook.Base) {
+	book := newBookSnapshot(x)
+	bot.books.Store(newMarketKey(e.GetName(), x.Asset, x.Pair), book)
+	bot.consolidate(e, book)
+
+	if p, ok := bot.entry(e).(paperExchange); ok && bot.paper.simulateFills {
+		p.match(x)
+	}
+
+	bot.triggerConditionals(e, x.Asset, x.Pair, priceFromBook(x))
 }
 
 // +----------------------+
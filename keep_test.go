@@ -2,8 +2,14 @@ package dola_test
 
 import (
 	"context"
+	"testing"
+	"time"
 
 	"github.com/numeusxyz/dola"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/ticker"
 )
 
 func ExampleKeep() {
@@ -11,3 +17,103 @@ func ExampleKeep() {
 	keep.Root.Add("verbose", dola.VerboseStrategy{}) //nolint:exhaustivestruct
 	keep.Run(context.Background())
 }
+
+// nolint: exhaustivestruct
+func TestKeep_CancelOrdersByPrefix(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k    dola.Keep
+		e    = newFakeExchange("fake")
+		pair = currency.NewPair(currency.BTC, currency.USDT)
+	)
+
+	e.active = []order.Detail{
+		{ID: "1", ClientOrderID: "mm-abc", Pair: pair, AssetType: asset.Spot},
+		{ID: "2", ClientOrderID: "arb-def", Pair: pair, AssetType: asset.Spot},
+		{ID: "3", ClientOrderID: "mm-ghi", Pair: pair, AssetType: asset.Spot},
+	}
+
+	if err := k.CancelOrdersByPrefix(context.Background(), e, order.Cancel{
+		Type:      order.AnyType,
+		Side:      order.AnySide,
+		AssetType: asset.Spot,
+		Pair:      pair,
+	}, "mm-"); err != nil {
+		t.Fatal(err)
+	}
+
+	xs := e.cancellations()
+	if len(xs) != 2 || xs[0].ID != "1" || xs[1].ID != "3" {
+		t.Errorf("unexpected cancellations: %+v", xs)
+	}
+}
+
+// nolint: exhaustivestruct
+func TestKeep_CancelOrdersWhere(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k dola.Keep
+		e = newFakeExchange("fake")
+	)
+
+	e.active = []order.Detail{
+		{ID: "1", Price: 100},
+		{ID: "2", Price: 200},
+	}
+
+	expensive := func(x order.Detail) bool { return x.Price > 150 }
+
+	results, err := k.CancelOrdersWhere(context.Background(), e, order.GetOrdersRequest{}, expensive, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 1 || results[0].Order.ID != "2" || len(e.cancellations()) != 0 {
+		t.Errorf("unexpected dry run: %+v, %+v", results, e.cancellations())
+	}
+
+	results, err = k.CancelOrdersWhere(context.Background(), e, order.GetOrdersRequest{}, expensive, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 1 || results[0].Err != nil {
+		t.Errorf("unexpected results: %+v", results)
+	}
+
+	if xs := e.cancellations(); len(xs) != 1 || xs[0].ID != "2" {
+		t.Errorf("unexpected cancellations: %+v", xs)
+	}
+}
+
+// nolint: exhaustivestruct
+func TestKeep_LastTicker(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k       dola.Keep
+		e       = newFakeExchange("fake")
+		pair    = currency.NewPair(currency.BTC, currency.USDT)
+		updated = time.Now().Add(-time.Second)
+	)
+
+	if _, ok := k.LastTicker("fake", asset.Spot, pair); ok {
+		t.Fatal("unexpected ticker")
+	}
+
+	k.OnPrice(e, ticker.Price{Last: 100, Pair: pair, AssetType: asset.Spot, LastUpdated: updated})
+
+	x, ok := k.LastTicker("fake", asset.Spot, pair)
+	if !ok || x.Last != 100 || !x.LastUpdated.Equal(updated) || x.Received.Before(updated) || x.Stale {
+		t.Errorf("unexpected ticker: %+v", x)
+	}
+
+	k.SetTickerStaleness(time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	if x, _ := k.LastTicker("fake", asset.Spot, pair); !x.Stale {
+		t.Error("ticker not stale")
+	}
+}
@@ -5,16 +5,65 @@ import (
 	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
 )
 
+// Observers are resolved through the UserData attached to an order with
+// Keep.SubmitOrderUD.  Each of them is invoked from Keep.OnOrder, i.e. from the
+// same thread that delivers the exchange's Strategy.On*() events.
+
+type OnAcknowledgedObserver interface {
+	OnAcknowledged(k *Keep, e exchange.IBotExchange, x order.Detail)
+}
+
+type OnPartiallyFilledObserver interface {
+	// OnPartiallyFilled receives the amount executed since the previous update.
+	OnPartiallyFilled(k *Keep, e exchange.IBotExchange, x order.Detail, delta float64)
+}
+
 type OnFilledObserver interface {
 	OnFilled(k *Keep, e exchange.IBotExchange, x order.Detail)
 }
 
+type OnCancelledObserver interface {
+	OnCancelled(k *Keep, e exchange.IBotExchange, x order.Detail)
+}
+
+type OnRejectedObserver interface {
+	OnRejected(k *Keep, e exchange.IBotExchange, x order.Detail)
+}
+
+type OnExpiredObserver interface {
+	OnExpired(k *Keep, e exchange.IBotExchange, x order.Detail)
+}
+
+type OnAmendedObserver interface {
+	OnAmended(k *Keep, e exchange.IBotExchange, x order.Detail)
+}
+
 // +-------+
 // | Slots |
 // +-------+
 
 type Slots struct {
-	OnFilledSlot func(k *Keep, e exchange.IBotExchange, x order.Detail)
+	OnAcknowledgedSlot    func(k *Keep, e exchange.IBotExchange, x order.Detail)
+	OnPartiallyFilledSlot func(k *Keep, e exchange.IBotExchange, x order.Detail, delta float64)
+	OnFilledSlot          func(k *Keep, e exchange.IBotExchange, x order.Detail)
+	OnCancelledSlot       func(k *Keep, e exchange.IBotExchange, x order.Detail)
+	OnRejectedSlot        func(k *Keep, e exchange.IBotExchange, x order.Detail)
+	OnExpiredSlot         func(k *Keep, e exchange.IBotExchange, x order.Detail)
+	OnAmendedSlot         func(k *Keep, e exchange.IBotExchange, x order.Detail)
+}
+
+// OnAcknowledged implements OnAcknowledgedObserver.
+func (s Slots) OnAcknowledged(k *Keep, e exchange.IBotExchange, x order.Detail) {
+	if s.OnAcknowledgedSlot != nil {
+		s.OnAcknowledgedSlot(k, e, x)
+	}
+}
+
+// OnPartiallyFilled implements OnPartiallyFilledObserver.
+func (s Slots) OnPartiallyFilled(k *Keep, e exchange.IBotExchange, x order.Detail, delta float64) {
+	if s.OnPartiallyFilledSlot != nil {
+		s.OnPartiallyFilledSlot(k, e, x, delta)
+	}
 }
 
 // OnFilled implements OnFilledObserver.
@@ -23,3 +72,109 @@ func (s Slots) OnFilled(k *Keep, e exchange.IBotExchange, x order.Detail) {
 		s.OnFilledSlot(k, e, x)
 	}
 }
+
+// OnCancelled implements OnCancelledObserver.
+func (s Slots) OnCancelled(k *Keep, e exchange.IBotExchange, x order.Detail) {
+	if s.OnCancelledSlot != nil {
+		s.OnCancelledSlot(k, e, x)
+	}
+}
+
+// OnRejected implements OnRejectedObserver.
+func (s Slots) OnRejected(k *Keep, e exchange.IBotExchange, x order.Detail) {
+	if s.OnRejectedSlot != nil {
+		s.OnRejectedSlot(k, e, x)
+	}
+}
+
+// OnExpired implements OnExpiredObserver.
+func (s Slots) OnExpired(k *Keep, e exchange.IBotExchange, x order.Detail) {
+	if s.OnExpiredSlot != nil {
+		s.OnExpiredSlot(k, e, x)
+	}
+}
+
+// OnAmended implements OnAmendedObserver.
+func (s Slots) OnAmended(k *Keep, e exchange.IBotExchange, x order.Detail) {
+	if s.OnAmendedSlot != nil {
+		s.OnAmendedSlot(k, e, x)
+	}
+}
+
+// +-----------+
+// | Lifecycle |
+// +-----------+
+
+// notifyObservers compares the previous and the current state of an order and
+// invokes the matching observers implemented by userData.  A zero prev.Status
+// means no update has been seen for this order yet.
+//
+// nolint: cyclop
+func notifyObservers(k *Keep, e exchange.IBotExchange, userData interface{}, prev, x order.Detail) {
+	first := prev.Status == ""
+	changed := first || prev.Status != x.Status
+
+	if first && !isRejected(x.Status) {
+		if obs, ok := userData.(OnAcknowledgedObserver); ok {
+			obs.OnAcknowledged(k, e, x)
+		}
+	}
+
+	if !first && x.IsActive() && isAmended(prev, x) {
+		if obs, ok := userData.(OnAmendedObserver); ok {
+			obs.OnAmended(k, e, x)
+		}
+	}
+
+	if delta := executedAmount(x) - executedAmount(prev); delta > 0 && x.Status != order.Filled {
+		if obs, ok := userData.(OnPartiallyFilledObserver); ok {
+			obs.OnPartiallyFilled(k, e, x, delta)
+		}
+	}
+
+	if !changed {
+		return
+	}
+
+	switch {
+	case x.Status == order.Filled:
+		if obs, ok := userData.(OnFilledObserver); ok {
+			obs.OnFilled(k, e, x)
+		}
+	case x.Status == order.Cancelled || x.Status == order.PartiallyCancelled:
+		if obs, ok := userData.(OnCancelledObserver); ok {
+			obs.OnCancelled(k, e, x)
+		}
+	case x.Status == order.Expired:
+		if obs, ok := userData.(OnExpiredObserver); ok {
+			obs.OnExpired(k, e, x)
+		}
+	case isRejected(x.Status):
+		if obs, ok := userData.(OnRejectedObserver); ok {
+			obs.OnRejected(k, e, x)
+		}
+	}
+}
+
+func isRejected(s order.Status) bool {
+	return s == order.Rejected || s == order.InsufficientBalance || s == order.MarketUnavailable
+}
+
+func isAmended(prev, x order.Detail) bool {
+	return (x.Price > 0 && x.Price != prev.Price) || (x.Amount > 0 && x.Amount != prev.Amount)
+}
+
+// executedAmount returns the executed amount of an order, deriving it from the
+// remaining amount if the exchange doesn't report it directly.
+func executedAmount(x order.Detail) float64 {
+	switch {
+	case x.ExecutedAmount > 0:
+		return x.ExecutedAmount
+	case x.Status == order.Filled:
+		return x.Amount
+	case x.RemainingAmount > 0 && x.Amount > x.RemainingAmount:
+		return x.Amount - x.RemainingAmount
+	default:
+		return 0
+	}
+}
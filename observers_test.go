@@ -0,0 +1,128 @@
+package dola_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/numeusxyz/dola"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+)
+
+// nolint: funlen
+func TestKeep_OnOrderLifecycle(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k      dola.Keep
+		e      = newFakeExchange("fake")
+		events []string
+		deltas []float64
+	)
+
+	record := func(event string) func(*dola.Keep, exchange.IBotExchange, order.Detail) {
+		return func(*dola.Keep, exchange.IBotExchange, order.Detail) {
+			events = append(events, event)
+		}
+	}
+
+	slots := dola.Slots{
+		OnAcknowledgedSlot: record("acknowledged"),
+		OnPartiallyFilledSlot: func(_ *dola.Keep, _ exchange.IBotExchange, _ order.Detail, delta float64) {
+			events = append(events, "partially filled")
+			deltas = append(deltas, delta)
+		},
+		OnFilledSlot:    record("filled"),
+		OnCancelledSlot: record("cancelled"),
+		OnRejectedSlot:  record("rejected"),
+		OnExpiredSlot:   record("expired"),
+		OnAmendedSlot:   record("amended"),
+	}
+
+	submit := order.Submit{Amount: 10, Price: 100} // nolint: exhaustivestruct
+
+	resp, err := k.SubmitOrderUD(context.Background(), e, submit, slots)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	update := func(status order.Status, price, amount, executed float64) {
+		k.OnOrder(e, order.Detail{ // nolint: exhaustivestruct
+			ID:             resp.OrderID,
+			Status:         status,
+			Price:          price,
+			Amount:         amount,
+			ExecutedAmount: executed,
+		})
+	}
+
+	update(order.New, 100, 10, 0)
+	update(order.PartiallyFilled, 100, 10, 2)
+	update(order.Active, 101, 10, 2)
+	update(order.PartiallyFilled, 101, 10, 5)
+	update(order.Filled, 101, 10, 10)
+	update(order.Filled, 101, 10, 10)
+
+	want := []string{
+		"acknowledged",
+		"partially filled",
+		"amended",
+		"partially filled",
+		"filled",
+	}
+	if diff := cmp.Diff(want, events); diff != "" {
+		t.Error(diff)
+	}
+
+	if diff := cmp.Diff([]float64{2, 3}, deltas); diff != "" {
+		t.Error(diff)
+	}
+
+	// Orders without user data in the registry are ignored.
+	k.OnOrder(e, order.Detail{ID: "unknown", Status: order.Cancelled}) // nolint: exhaustivestruct
+
+	if len(events) != len(want) {
+		t.Errorf("have %d events, want %d", len(events), len(want))
+	}
+}
+
+func TestKeep_OnOrderTerminal(t *testing.T) {
+	t.Parallel()
+
+	for status, want := range map[order.Status]string{
+		order.Cancelled:           "cancelled",
+		order.Rejected:            "rejected",
+		order.InsufficientBalance: "rejected",
+		order.Expired:             "expired",
+	} {
+		var (
+			k    dola.Keep
+			e    = newFakeExchange("fake")
+			have []string
+		)
+
+		record := func(event string) func(*dola.Keep, exchange.IBotExchange, order.Detail) {
+			return func(*dola.Keep, exchange.IBotExchange, order.Detail) {
+				have = append(have, event)
+			}
+		}
+
+		slots := dola.Slots{ // nolint: exhaustivestruct
+			OnCancelledSlot: record("cancelled"),
+			OnRejectedSlot:  record("rejected"),
+			OnExpiredSlot:   record("expired"),
+		}
+
+		resp, err := k.SubmitOrderUD(context.Background(), e, order.Submit{}, slots) // nolint: exhaustivestruct
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		k.OnOrder(e, order.Detail{ID: resp.OrderID, Status: status}) // nolint: exhaustivestruct
+
+		if diff := cmp.Diff([]string{want}, have); diff != "" {
+			t.Errorf("%s: %s", status, diff)
+		}
+	}
+}
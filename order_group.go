@@ -0,0 +1,489 @@
+package dola
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+	"go.uber.org/multierr"
+)
+
+// +------------+
+// | OrderGroup |
+// +------------+
+
+var ErrOrderGroupNotFound = errors.New("order group not found")
+
+type OrderGroupState int
+
+const (
+	// GroupPending means a bracket is waiting for its entry to fill.
+	GroupPending OrderGroupState = iota
+	// GroupActive means all legs are live.
+	GroupActive
+	// GroupDone means one leg got filled and its siblings got cancelled.
+	GroupDone
+	// GroupOverfilled means more than one leg got filled, i.e. siblings
+	// couldn't be cancelled in time.
+	GroupOverfilled
+	// GroupCancelled means no leg got filled.
+	GroupCancelled
+)
+
+// OrderLeg is a member of an OrderGroup.  Legs with a zero Trigger are submitted
+// as regular orders, the rest as conditional orders (see ConditionalOrder).
+type OrderLeg struct {
+	Submit    order.Submit
+	Trigger   float64
+	Direction TriggerDirection
+}
+
+// Leg returns a leg that is submitted as a regular order.
+func Leg(x order.Submit) OrderLeg {
+	return OrderLeg{
+		Submit:    x,
+		Trigger:   0,
+		Direction: TriggerAtOrAbove,
+	}
+}
+
+// ConditionalLeg returns a leg that is submitted as a conditional order, e.g.
+// ConditionalLeg(StopLoss(x, price)).
+func ConditionalLeg(c ConditionalOrder) OrderLeg {
+	return OrderLeg{
+		Submit:    c.Submit,
+		Trigger:   c.Trigger,
+		Direction: c.Direction,
+	}
+}
+
+// OrderGroup links orders whose lifecycles depend on each other: one-cancels-
+// other pairs and brackets.  Each leg is stored in the registry with a groupLeg
+// as its UserData, which forwards all order events to the group's UserData.
+type OrderGroup struct {
+	ID       string
+	Exchange string
+	UserData interface{}
+
+	exchange exchange.IBotExchange
+
+	mu     sync.Mutex
+	state  OrderGroupState
+	entry  *groupLeg
+	exits  []OrderLeg
+	legs   []*groupLeg
+	filled *groupLeg
+}
+
+// State returns the current state of the group.
+func (g *OrderGroup) State() OrderGroupState {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.state
+}
+
+// Cancel cancels all live legs of the group, including a bracket's entry.
+func (g *OrderGroup) Cancel(ctx context.Context, k *Keep) error {
+	g.mu.Lock()
+
+	if g.state == GroupPending || g.state == GroupActive {
+		g.state = GroupCancelled
+	}
+
+	g.exits = nil
+	legs := append([]*groupLeg{}, g.legs...)
+
+	if g.entry != nil {
+		legs = append(legs, g.entry)
+	}
+
+	for _, gl := range legs {
+		gl.cancelling = true
+	}
+
+	g.mu.Unlock()
+
+	var multi error
+
+	for _, gl := range legs {
+		multi = multierr.Append(multi, gl.cancel(ctx, k))
+	}
+
+	return multi
+}
+
+// groupLeg tracks a single leg.  All fields are protected by group.mu.
+type groupLeg struct {
+	group *OrderGroup
+	leg   OrderLeg
+
+	conditionalID string
+	orderID       string
+	closed        bool
+	cancelling    bool
+}
+
+// cancel cancels the leg, wherever it is in its lifecycle.  If a conditional leg
+// has already been triggered but its order isn't acknowledged yet, the order
+// gets cancelled on acknowledgement (see OnAcknowledged).
+func (gl *groupLeg) cancel(ctx context.Context, k *Keep) error {
+	gl.group.mu.Lock()
+	closed, orderID, conditionalID := gl.closed, gl.orderID, gl.conditionalID
+	gl.group.mu.Unlock()
+
+	switch {
+	case closed:
+		return nil
+	case orderID != "":
+		// nolint: exhaustivestruct
+		return k.CancelOrder(ctx, gl.group.exchange, order.Cancel{
+			Exchange:  gl.group.Exchange,
+			ID:        orderID,
+			Side:      gl.leg.Submit.Side,
+			AssetType: gl.leg.Submit.AssetType,
+			Pair:      gl.leg.Submit.Pair,
+		})
+	case conditionalID != "":
+		if err := k.CancelConditional(gl.group.exchange, conditionalID); !errors.Is(err, ErrConditionalNotFound) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (gl *groupLeg) submit(ctx context.Context, k *Keep, e exchange.IBotExchange) error {
+	gl.group.mu.Lock()
+	cancelled := gl.cancelling
+	gl.group.mu.Unlock()
+
+	// A sibling may have been filled while the previous legs were submitted.
+	if cancelled {
+		return nil
+	}
+
+	if gl.leg.Trigger == 0 {
+		resp, err := k.SubmitOrderUD(ctx, e, gl.leg.Submit, gl)
+		if err != nil {
+			return err
+		}
+
+		gl.group.mu.Lock()
+		gl.orderID = resp.OrderID
+		gl.group.mu.Unlock()
+
+		return nil
+	}
+
+	// The ID is assigned upfront as SubmitConditional acknowledges the order
+	// before returning.
+	gl.group.mu.Lock()
+	gl.conditionalID = RandomOrderID("cond-")
+	gl.group.mu.Unlock()
+
+	// nolint: exhaustivestruct
+	_, err := k.SubmitConditional(e, ConditionalOrder{
+		ID:        gl.conditionalID,
+		Exchange:  e.GetName(),
+		Trigger:   gl.leg.Trigger,
+		Direction: gl.leg.Direction,
+		Submit:    gl.leg.Submit,
+	}, gl)
+
+	return err
+}
+
+// +------------------------+
+// | groupLeg: observations |
+// +------------------------+
+
+func (gl *groupLeg) OnAcknowledged(k *Keep, e exchange.IBotExchange, x order.Detail) {
+	g := gl.group
+	g.mu.Lock()
+
+	// Triggered conditional legs get a new order ID.
+	if x.ID != gl.conditionalID {
+		gl.orderID = x.ID
+	}
+
+	cancel := gl.cancelling && gl.orderID != ""
+
+	g.mu.Unlock()
+
+	if cancel {
+		go gl.cancelOrLog(k)
+	}
+
+	if obs, ok := g.UserData.(OnAcknowledgedObserver); ok {
+		obs.OnAcknowledged(k, e, x)
+	}
+}
+
+func (gl *groupLeg) OnPartiallyFilled(k *Keep, e exchange.IBotExchange, x order.Detail, delta float64) {
+	gl.onFill(k, e, x)
+
+	if obs, ok := gl.group.UserData.(OnPartiallyFilledObserver); ok {
+		obs.OnPartiallyFilled(k, e, x, delta)
+	}
+}
+
+func (gl *groupLeg) OnFilled(k *Keep, e exchange.IBotExchange, x order.Detail) {
+	gl.group.mu.Lock()
+	gl.closed = true
+	gl.group.mu.Unlock()
+
+	gl.onFill(k, e, x)
+
+	if obs, ok := gl.group.UserData.(OnFilledObserver); ok {
+		obs.OnFilled(k, e, x)
+	}
+}
+
+func (gl *groupLeg) OnCancelled(k *Keep, e exchange.IBotExchange, x order.Detail) {
+	gl.onClosed(k, e, x)
+
+	if obs, ok := gl.group.UserData.(OnCancelledObserver); ok {
+		obs.OnCancelled(k, e, x)
+	}
+}
+
+func (gl *groupLeg) OnRejected(k *Keep, e exchange.IBotExchange, x order.Detail) {
+	gl.onClosed(k, e, x)
+
+	if obs, ok := gl.group.UserData.(OnRejectedObserver); ok {
+		obs.OnRejected(k, e, x)
+	}
+}
+
+func (gl *groupLeg) OnExpired(k *Keep, e exchange.IBotExchange, x order.Detail) {
+	gl.onClosed(k, e, x)
+
+	if obs, ok := gl.group.UserData.(OnExpiredObserver); ok {
+		obs.OnExpired(k, e, x)
+	}
+}
+
+func (gl *groupLeg) OnAmended(k *Keep, e exchange.IBotExchange, x order.Detail) {
+	if obs, ok := gl.group.UserData.(OnAmendedObserver); ok {
+		obs.OnAmended(k, e, x)
+	}
+}
+
+// onFill handles any (partial or full) fill of a leg.  A filled entry places
+// the exits, a filled exit cancels its siblings.
+func (gl *groupLeg) onFill(k *Keep, e exchange.IBotExchange, x order.Detail) {
+	g := gl.group
+	g.mu.Lock()
+
+	if gl == g.entry {
+		g.mu.Unlock()
+
+		if x.Status == order.Filled {
+			g.placeExits(k, e, executedAmount(x))
+		}
+
+		return
+	}
+
+	var siblings []*groupLeg
+
+	switch {
+	case g.filled == nil:
+		g.filled = gl
+		g.state = GroupDone
+
+		for _, sibling := range g.legs {
+			if sibling != gl && !sibling.closed {
+				sibling.cancelling = true
+				siblings = append(siblings, sibling)
+			}
+		}
+	case g.filled != gl && g.state != GroupOverfilled:
+		g.state = GroupOverfilled
+
+		What(log.Error().Str("exchange", e.GetName()).Str("group", g.ID), "more than one leg filled")
+		k.ReportEvent(OrderGroupOverfillMetric, e.GetName())
+	}
+
+	g.mu.Unlock()
+
+	for _, sibling := range siblings {
+		go sibling.cancelOrLog(k)
+	}
+}
+
+// onClosed handles legs that are done without being filled.  An entry that is
+// closed with a partial fill still places the exits, sized accordingly.
+func (gl *groupLeg) onClosed(k *Keep, e exchange.IBotExchange, x order.Detail) {
+	g := gl.group
+	g.mu.Lock()
+
+	gl.closed = true
+
+	if gl == g.entry {
+		g.mu.Unlock()
+
+		if executed := executedAmount(x); executed > 0 {
+			g.placeExits(k, e, executed)
+		} else {
+			g.mu.Lock()
+			g.exits = nil
+			g.state = GroupCancelled
+			g.mu.Unlock()
+		}
+
+		return
+	}
+
+	allClosed := true
+
+	for _, leg := range g.legs {
+		allClosed = allClosed && leg.closed
+	}
+
+	if allClosed && g.filled == nil {
+		g.state = GroupCancelled
+	}
+
+	g.mu.Unlock()
+}
+
+func (gl *groupLeg) cancelOrLog(k *Keep) {
+	if err := gl.cancel(context.Background(), k); err != nil {
+		What(log.Error().Err(err).Str("group", gl.group.ID), "unable to cancel order group leg")
+	}
+}
+
+// placeExits submits the exits of a bracket as a one-cancels-other group, sized
+// to the executed amount of the entry.  Submission happens in the background so
+// as not to block the exchange's stream.
+func (g *OrderGroup) placeExits(k *Keep, e exchange.IBotExchange, amount float64) {
+	g.mu.Lock()
+
+	exits := g.exits
+	g.exits = nil
+
+	if len(exits) == 0 {
+		g.mu.Unlock()
+
+		return
+	}
+
+	g.state = GroupActive
+
+	for _, exit := range exits {
+		exit.Submit.Amount = amount
+		g.legs = append(g.legs, &groupLeg{ // nolint: exhaustivestruct
+			group: g,
+			leg:   exit,
+		})
+	}
+
+	legs := append([]*groupLeg{}, g.legs...)
+
+	g.mu.Unlock()
+
+	go func() {
+		if err := g.submitLegs(context.Background(), k, e, legs); err != nil {
+			What(log.Error().Err(err).Str("group", g.ID), "unable to place bracket exits")
+		}
+	}()
+}
+
+// submitLegs submits all legs.  If any of them fails, the ones already
+// submitted get cancelled.
+func (g *OrderGroup) submitLegs(ctx context.Context, k *Keep, e exchange.IBotExchange, legs []*groupLeg) error {
+	for i, gl := range legs {
+		if err := gl.submit(ctx, k, e); err != nil {
+			g.mu.Lock()
+			g.state = GroupCancelled
+			gl.closed = true
+			g.mu.Unlock()
+
+			for _, submitted := range legs[:i] {
+				err = multierr.Append(err, submitted.cancel(ctx, k))
+			}
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+// +--------------------+
+// | Keep: Order groups |
+// +--------------------+
+
+// SubmitOCO submits two one-cancels-other orders.  As soon as either gets (even
+// partially) filled, the other one gets cancelled.  All order events are
+// forwarded to userData.
+func (bot *Keep) SubmitOCO(ctx context.Context,
+	exchangeOrName interface{},
+	a, b OrderLeg,
+	userData interface{}) (*OrderGroup, error) {
+	e := bot.getExchange(exchangeOrName)
+	g := bot.newOrderGroup(e, userData)
+	g.state = GroupActive
+	g.legs = []*groupLeg{
+		{group: g, leg: a}, // nolint: exhaustivestruct
+		{group: g, leg: b}, // nolint: exhaustivestruct
+	}
+
+	if err := g.submitLegs(ctx, bot, e, g.legs); err != nil {
+		return g, err
+	}
+
+	return g, nil
+}
+
+// SubmitBracket submits entry and, once it fills, places stop and takeProfit as
+// a one-cancels-other pair sized to the filled amount.  Typically stop is a
+// ConditionalLeg(StopLoss(...)) and takeProfit a limit order.  All order events
+// are forwarded to userData.
+func (bot *Keep) SubmitBracket(ctx context.Context,
+	exchangeOrName interface{},
+	entry order.Submit,
+	stop, takeProfit OrderLeg,
+	userData interface{}) (*OrderGroup, error) {
+	e := bot.getExchange(exchangeOrName)
+	g := bot.newOrderGroup(e, userData)
+	g.entry = &groupLeg{group: g, leg: Leg(entry)} // nolint: exhaustivestruct
+	g.exits = []OrderLeg{stop, takeProfit}
+
+	if err := g.entry.submit(ctx, bot, e); err != nil {
+		g.state = GroupCancelled
+
+		return g, err
+	}
+
+	return g, nil
+}
+
+// OrderGroup returns a group submitted with SubmitOCO or SubmitBracket.
+func (bot *Keep) OrderGroup(id string) (*OrderGroup, error) {
+	if x, ok := bot.groups.Load(id); ok {
+		if g, ok := x.(*OrderGroup); ok {
+			return g, nil
+		}
+	}
+
+	return nil, ErrOrderGroupNotFound
+}
+
+func (bot *Keep) newOrderGroup(e exchange.IBotExchange, userData interface{}) *OrderGroup {
+	g := &OrderGroup{ // nolint: exhaustivestruct
+		ID:       RandomOrderID("grp-"),
+		Exchange: e.GetName(),
+		UserData: userData,
+		exchange: e,
+		state:    GroupPending,
+	}
+	bot.groups.Store(g.ID, g)
+
+	return g
+}
@@ -0,0 +1,167 @@
+package dola_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/numeusxyz/dola"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+)
+
+func waitFor(cond func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !cond() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// nolint: exhaustivestruct
+func TestKeep_SubmitOCO(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k     dola.Keep
+		e     = newFakeExchange("fake")
+		pair  = currency.NewPair(currency.BTC, currency.USDT)
+		limit = func(price float64) order.Submit {
+			return order.Submit{
+				Amount:    1,
+				Price:     price,
+				Type:      order.Limit,
+				Side:      order.Sell,
+				Pair:      pair,
+				AssetType: asset.Spot,
+			}
+		}
+		filled = func(id string) order.Detail {
+			return order.Detail{
+				ID:             id,
+				Amount:         1,
+				ExecutedAmount: 1,
+				Status:         order.Filled,
+				Pair:           pair,
+				AssetType:      asset.Spot,
+			}
+		}
+	)
+
+	g, err := k.SubmitOCO(context.Background(), e, dola.Leg(limit(110)), dola.Leg(limit(120)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if state := g.State(); state != dola.GroupActive {
+		t.Errorf("have state %v, want %v", state, dola.GroupActive)
+	}
+
+	k.OnOrder(e, filled("1"))
+	waitFor(func() bool { return len(e.cancellations()) > 0 })
+
+	if xs := e.cancellations(); len(xs) != 1 || xs[0].ID != "2" {
+		t.Errorf("unexpected cancellations: %+v", xs)
+	}
+
+	if state := g.State(); state != dola.GroupDone {
+		t.Errorf("have state %v, want %v", state, dola.GroupDone)
+	}
+
+	// The sibling got filled before the cancellation went through.
+	k.OnOrder(e, filled("2"))
+
+	if state := g.State(); state != dola.GroupOverfilled {
+		t.Errorf("have state %v, want %v", state, dola.GroupOverfilled)
+	}
+}
+
+// nolint: exhaustivestruct
+func TestKeep_SubmitBracket(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k     dola.Keep
+		e     = newFakeExchange("fake")
+		pair  = currency.NewPair(currency.BTC, currency.USDT)
+		entry = order.Submit{
+			Amount:    2,
+			Price:     100,
+			Type:      order.Limit,
+			Side:      order.Buy,
+			Pair:      pair,
+			AssetType: asset.Spot,
+		}
+		exit = order.Submit{
+			Amount:    2,
+			Type:      order.Market,
+			Side:      order.Sell,
+			Pair:      pair,
+			AssetType: asset.Spot,
+		}
+		takeProfit = order.Submit{
+			Amount:    2,
+			Price:     120,
+			Type:      order.Limit,
+			Side:      order.Sell,
+			Pair:      pair,
+			AssetType: asset.Spot,
+		}
+	)
+
+	g, err := k.SubmitBracket(context.Background(), e, entry,
+		dola.ConditionalLeg(dola.StopLoss(exit, 90)), dola.Leg(takeProfit), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if state := g.State(); state != dola.GroupPending {
+		t.Errorf("have state %v, want %v", state, dola.GroupPending)
+	}
+
+	// The entry is cancelled after a partial fill, so the exits are sized down.
+	k.OnOrder(e, order.Detail{
+		ID:             "1",
+		Amount:         2,
+		ExecutedAmount: 1.5,
+		Status:         order.Cancelled,
+		Pair:           pair,
+		AssetType:      asset.Spot,
+	})
+	waitFor(func() bool { return len(e.submissions()) == 2 })
+
+	if xs := e.submissions(); len(xs) != 2 || xs[1].Price != 120 || xs[1].Amount != 1.5 {
+		t.Fatalf("unexpected submissions: %+v", xs)
+	}
+
+	if cs := k.ConditionalOrders(); len(cs) != 1 || cs[0].Submit.Amount != 1.5 || cs[0].Trigger != 90 {
+		t.Fatalf("unexpected conditional orders: %+v", cs)
+	}
+
+	if state := g.State(); state != dola.GroupActive {
+		t.Errorf("have state %v, want %v", state, dola.GroupActive)
+	}
+
+	// Filling the take profit cancels the stop.
+	k.OnOrder(e, order.Detail{
+		ID:             "2",
+		Amount:         1.5,
+		ExecutedAmount: 1.5,
+		Status:         order.Filled,
+		Pair:           pair,
+		AssetType:      asset.Spot,
+	})
+	waitFor(func() bool { return len(k.ConditionalOrders()) == 0 })
+
+	if n := len(k.ConditionalOrders()); n != 0 {
+		t.Errorf("have %d conditional orders, want 0", n)
+	}
+
+	if state := g.State(); state != dola.GroupDone {
+		t.Errorf("have state %v, want %v", state, dola.GroupDone)
+	}
+
+	if _, err := k.OrderGroup(g.ID); err != nil {
+		t.Error(err)
+	}
+}
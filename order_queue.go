@@ -0,0 +1,95 @@
+package dola
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+)
+
+// +------------+
+// | OrderQueue |
+// +------------+
+
+// orderQueues holds the order updates Keep emits itself, e.g. in dry-run mode
+// or for synthetic orders, per exchange.
+type orderQueues struct {
+	mu sync.Mutex
+	// queues maps a lower-cased exchange name to its pending order updates.
+	queues map[string]*orderQueue
+}
+
+// get returns the queue of an exchange's order updates, creating it if need be.
+func (qs *orderQueues) get(exchangeName string) *orderQueue {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	if qs.queues == nil {
+		qs.queues = make(map[string]*orderQueue)
+	}
+
+	name := strings.ToLower(exchangeName)
+
+	q, ok := qs.queues[name]
+	if !ok {
+		q = &orderQueue{
+			mu:      sync.Mutex{},
+			updates: []order.Detail{},
+			ready:   make(chan struct{}, 1),
+		}
+		qs.queues[name] = q
+	}
+
+	return q
+}
+
+// orderQueue holds the order updates of an exchange until its stream goroutine
+// delivers them (see Loop), so that all events of an exchange keep coming from
+// a single goroutine.  It never blocks, as updates are often emitted from that
+// very goroutine.
+type orderQueue struct {
+	mu      sync.Mutex
+	updates []order.Detail
+	// ready is signalled whenever updates becomes non-empty.
+	ready chan struct{}
+}
+
+func (q *orderQueue) push(x order.Detail) {
+	q.mu.Lock()
+	q.updates = append(q.updates, x)
+	q.mu.Unlock()
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+func (q *orderQueue) pop() []order.Detail {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	xs := q.updates
+	q.updates = []order.Detail{}
+
+	return xs
+}
+
+// queueOrder queues an order update emitted by Keep itself for delivery through
+// Keep.OnOrder and Strategy.OnOrder from the exchange's stream goroutine.
+func (bot *Keep) queueOrder(e exchange.IBotExchange, x order.Detail) {
+	bot.updates.get(e.GetName()).push(x)
+}
+
+// deliverQueuedOrders hands the queued order updates of an exchange over to s,
+// in the order they were emitted.
+func (bot *Keep) deliverQueuedOrders(e exchange.IBotExchange, s Strategy) {
+	for _, x := range bot.updates.get(e.GetName()).pop() {
+		x := x
+		if err := handleData(bot, e, s, &x); err != nil {
+			What(log.Error().Err(err).Str("exchange", e.GetName()), "unable to deliver order update")
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"log"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
 )
@@ -14,32 +15,81 @@ type OrderKey struct {
 }
 
 type OrderValue struct {
+	// Submit is the request the order was submitted with.
+	Submit         order.Submit
 	SubmitResponse order.SubmitResponse
 	UserData       interface{}
+	// Detail is the latest order update observed through Keep.OnOrder.  Its
+	// Status is empty until the first update arrives.
+	Detail order.Detail
+	// Synthetic orders are managed client-side by Keep and never reach the
+	// exchange as such, e.g. conditional orders.
+	Synthetic bool
+	// Replaces and ReplacedBy link orders replaced through a cancel-replace.
+	Replaces   string
+	ReplacedBy string
+	// Stored is when the order was stored in the registry.
+	Stored time.Time
 }
 
 type OrderRegistry struct {
 	length int32
 	values sync.Map
+	// mu serializes read-modify-write updates of values.
+	mu sync.Mutex
 }
 
 func NewOrderRegistry() *OrderRegistry {
 	return &OrderRegistry{
 		length: 0,
 		values: sync.Map{},
+		mu:     sync.Mutex{},
+	}
+}
+
+// IsOpen reports whether the order may still be live on the exchange.  Orders
+// for which no update has been observed yet are considered open.
+func (v OrderValue) IsOpen() bool {
+	switch v.Detail.Status {
+	case order.Filled,
+		order.Cancelled,
+		order.PartiallyCancelled,
+		order.Expired,
+		order.Closed,
+		order.Rejected,
+		order.InsufficientBalance,
+		order.MarketUnavailable:
+		return false
+	default:
+		return true
 	}
 }
 
 // Store saves order details.  If such an order exists
 // (matched by exchange name and order ID), false is returned.
 func (r *OrderRegistry) Store(exchangeName string, response order.SubmitResponse, userData interface{}) bool {
-	key := OrderKey{
-		ExchangeName: exchangeName,
-		OrderID:      response.OrderID,
-	}
-	value := OrderValue{
+	return r.StoreValue(exchangeName, OrderValue{
+		Submit:         order.Submit{}, // nolint: exhaustivestruct
 		SubmitResponse: response,
 		UserData:       userData,
+		Detail:         order.Detail{}, // nolint: exhaustivestruct
+		Synthetic:      false,
+		Replaces:       "",
+		ReplacedBy:     "",
+		Stored:         time.Time{},
+	})
+}
+
+// StoreValue is like Store, but saves a fully populated OrderValue.  Stored
+// defaults to the current time.
+func (r *OrderRegistry) StoreValue(exchangeName string, value OrderValue) bool {
+	if value.Stored.IsZero() {
+		value.Stored = time.Now()
+	}
+
+	key := OrderKey{
+		ExchangeName: exchangeName,
+		OrderID:      value.SubmitResponse.OrderID,
 	}
 	_, loaded := r.values.LoadOrStore(key, value)
 
@@ -74,6 +124,110 @@ func (r *OrderRegistry) GetOrderValue(exchangeName, orderID string) (OrderValue,
 	return value, loaded
 }
 
+// Observe records x as the latest known state of its order and returns the
+// value as it was before the update.  If the order is not in the registry,
+// nothing is recorded and false is returned.
+func (r *OrderRegistry) Observe(exchangeName string, x order.Detail) (OrderValue, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	value, loaded := r.GetOrderValue(exchangeName, x.ID)
+	if !loaded {
+		return value, false
+	}
+
+	updated := value
+	updated.Detail = x
+
+	r.values.Store(OrderKey{ExchangeName: exchangeName, OrderID: x.ID}, updated)
+
+	return value, true
+}
+
+// SetUserData replaces the UserData of an order and returns the value as it was
+// before.  If the order is not in the registry, nothing is recorded and false is
+// returned.
+func (r *OrderRegistry) SetUserData(exchangeName, orderID string, userData interface{}) (OrderValue, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	value, loaded := r.GetOrderValue(exchangeName, orderID)
+	if !loaded {
+		return value, false
+	}
+
+	updated := value
+	updated.UserData = userData
+
+	r.values.Store(OrderKey{ExchangeName: exchangeName, OrderID: orderID}, updated)
+
+	return value, true
+}
+
+// Link records that oldID got replaced by newID.  The UserData of the old order
+// is detached, so that its observers are only notified of the new order.  If
+// either order isn't in the registry, nothing is recorded and false is returned.
+func (r *OrderRegistry) Link(exchangeName, oldID, newID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	prev, loaded := r.GetOrderValue(exchangeName, oldID)
+	if !loaded {
+		return false
+	}
+
+	next, loaded := r.GetOrderValue(exchangeName, newID)
+	if !loaded {
+		return false
+	}
+
+	if next.UserData == nil {
+		next.UserData = prev.UserData
+	}
+
+	prev.UserData = nil
+	prev.ReplacedBy = newID
+	next.Replaces = oldID
+
+	r.values.Store(OrderKey{ExchangeName: exchangeName, OrderID: oldID}, prev)
+	r.values.Store(OrderKey{ExchangeName: exchangeName, OrderID: newID}, next)
+
+	return true
+}
+
+// Lineage returns the chain of replaced orders leading to orderID, starting
+// with the original one.
+func (r *OrderRegistry) Lineage(exchangeName, orderID string) []string {
+	ids := []string{orderID}
+
+	for {
+		value, loaded := r.GetOrderValue(exchangeName, ids[0])
+		if !loaded || value.Replaces == "" {
+			return ids
+		}
+
+		ids = append([]string{value.Replaces}, ids...)
+	}
+}
+
+// Range calls f sequentially for each order in the registry.  If f returns
+// false, Range stops the iteration.
+func (r *OrderRegistry) Range(f func(key OrderKey, value OrderValue) bool) {
+	r.values.Range(func(key, value interface{}) bool {
+		k, ok := key.(OrderKey)
+		if !ok {
+			log.Fatalf("have %T, want OrderKey", key)
+		}
+
+		v, ok := value.(OrderValue)
+		if !ok {
+			log.Fatalf("have %T, want OrderValue", value)
+		}
+
+		return f(k, v)
+	})
+}
+
 func (r *OrderRegistry) Length() int {
 	return int(atomic.LoadInt32(&r.length))
 }
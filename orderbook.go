@@ -0,0 +1,134 @@
+package dola
+
+import (
+	"time"
+
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/orderbook"
+)
+
+// +--------------+
+// | BookSnapshot |
+// +--------------+
+
+// BookSnapshot is a copy of the latest order book seen for an instrument.  It is
+// never modified once cached, so it can be read from any goroutine.
+type BookSnapshot struct {
+	orderbook.Base
+	// Received is when Keep received the book.
+	Received time.Time
+}
+
+func newBookSnapshot(x orderbook.Base) BookSnapshot {
+	x.Bids = append([]orderbook.Item{}, x.Bids...)
+	x.Asks = append([]orderbook.Item{}, x.Asks...)
+
+	return BookSnapshot{
+		Base:     x,
+		Received: time.Now(),
+	}
+}
+
+func (b BookSnapshot) BestBid() (orderbook.Item, bool) {
+	if len(b.Bids) == 0 {
+		return orderbook.Item{}, false // nolint: exhaustivestruct
+	}
+
+	return b.Bids[0], true
+}
+
+func (b BookSnapshot) BestAsk() (orderbook.Item, bool) {
+	if len(b.Asks) == 0 {
+		return orderbook.Item{}, false // nolint: exhaustivestruct
+	}
+
+	return b.Asks[0], true
+}
+
+// Mid returns the price halfway between the best bid and ask.
+func (b BookSnapshot) Mid() (float64, bool) {
+	bid, okBid := b.BestBid()
+	ask, okAsk := b.BestAsk()
+
+	return (bid.Price + ask.Price) / 2, okBid && okAsk
+}
+
+// Spread returns the difference between the best ask and bid.
+func (b BookSnapshot) Spread() (float64, bool) {
+	bid, okBid := b.BestBid()
+	ask, okAsk := b.BestAsk()
+
+	return ask.Price - bid.Price, okBid && okAsk
+}
+
+// Depth returns up to n levels of each side.
+func (b BookSnapshot) Depth(n int) (bids, asks []orderbook.Item) {
+	if n < len(b.Bids) {
+		bids = b.Bids[:n]
+	} else {
+		bids = b.Bids
+	}
+
+	if n < len(b.Asks) {
+		asks = b.Asks[:n]
+	} else {
+		asks = b.Asks
+	}
+
+	return bids, asks
+}
+
+// VolumeTo returns the volume an order of the given side could take up to
+// price, i.e. asks at or below price for buys and bids at or above it for sells.
+func (b BookSnapshot) VolumeTo(side order.Side, price float64) float64 {
+	volume := 0.0
+
+	if isBuy(side) {
+		for _, x := range b.Asks {
+			if x.Price > price {
+				break
+			}
+
+			volume += x.Amount
+		}
+
+		return volume
+	}
+
+	for _, x := range b.Bids {
+		if x.Price < price {
+			break
+		}
+
+		volume += x.Amount
+	}
+
+	return volume
+}
+
+// Age returns the time since the exchange last updated the book or, if it
+// doesn't tell, since Keep received it.
+func (b BookSnapshot) Age() time.Duration {
+	if !b.LastUpdated.IsZero() {
+		return time.Since(b.LastUpdated)
+	}
+
+	return time.Since(b.Received)
+}
+
+// +-----------------+
+// | Keep: Orderbook |
+// +-----------------+
+
+// OrderBook returns the latest order book streamed for an instrument.
+func (bot *Keep) OrderBook(exchangeName string, a asset.Item, p currency.Pair) (BookSnapshot, bool) {
+	if x, ok := bot.books.Load(newMarketKey(exchangeName, a, p)); ok {
+		if book, ok := x.(BookSnapshot); ok {
+			return book, true
+		}
+	}
+
+	return BookSnapshot{}, false // nolint: exhaustivestruct
+}
@@ -0,0 +1,73 @@
+package dola_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/numeusxyz/dola"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/orderbook"
+)
+
+// nolint: exhaustivestruct
+func TestKeep_OrderBook(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k    dola.Keep
+		e    = newFakeExchange("fake")
+		pair = currency.NewPair(currency.BTC, currency.USDT)
+		bids = []orderbook.Item{{Price: 99, Amount: 1}, {Price: 98, Amount: 2}, {Price: 97, Amount: 3}}
+		asks = []orderbook.Item{{Price: 101, Amount: 1}, {Price: 102, Amount: 2}}
+	)
+
+	if _, ok := k.OrderBook("fake", asset.Spot, pair); ok {
+		t.Fatal("unexpected book")
+	}
+
+	k.OnOrderBook(e, orderbook.Base{
+		Bids:        bids,
+		Asks:        asks,
+		Pair:        pair,
+		Asset:       asset.Spot,
+		LastUpdated: time.Now().Add(-time.Minute),
+	})
+
+	// The cached book is a copy.
+	bids[0].Price = 0
+
+	book, ok := k.OrderBook("FAKE", asset.Spot, currency.NewPairWithDelimiter("btc", "usdt", "-"))
+	if !ok {
+		t.Fatal("book not cached")
+	}
+
+	if bid, _ := book.BestBid(); bid.Price != 99 {
+		t.Errorf("have best bid %v, want 99", bid.Price)
+	}
+
+	if mid, ok := book.Mid(); !ok || mid != 100 {
+		t.Errorf("have mid %v, want 100", mid)
+	}
+
+	if spread, ok := book.Spread(); !ok || spread != 2 {
+		t.Errorf("have spread %v, want 2", spread)
+	}
+
+	if bids, asks := book.Depth(2); len(bids) != 2 || len(asks) != 2 {
+		t.Errorf("have depth %d/%d, want 2/2", len(bids), len(asks))
+	}
+
+	if v := book.VolumeTo(order.Sell, 98); v != 3 {
+		t.Errorf("have volume %v, want 3", v)
+	}
+
+	if v := book.VolumeTo(order.Buy, 101.5); v != 1 {
+		t.Errorf("have volume %v, want 1", v)
+	}
+
+	if age := book.Age(); age < time.Minute {
+		t.Errorf("have age %v, want at least a minute", age)
+	}
+}
@@ -0,0 +1,241 @@
+package dola
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// +-------------+
+// | RateLimiter |
+// +-------------+
+
+// RequestType classifies order requests for rate limiting.  Lower values take
+// priority over higher ones.
+type RequestType int
+
+const (
+	CancelRequest RequestType = iota
+	ModifyRequest
+	SubmitRequest
+	// this should always be the last one.
+	maxRequestTypes
+)
+
+// Rate configures a token bucket that holds up to Burst tokens and is refilled
+// at PerSecond tokens per second.  The zero Rate means no limit.
+type Rate struct {
+	PerSecond float64
+	Burst     int
+}
+
+// RateLimits configures an OrderScheduler.  Total is shared among all request
+// types, on top of the per-type limits.
+type RateLimits struct {
+	Total  Rate
+	Cancel Rate
+	Modify Rate
+	Submit Rate
+}
+
+type tokenBucket struct {
+	rate   Rate
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(r Rate, now time.Time) *tokenBucket {
+	if r.PerSecond <= 0 {
+		return nil
+	}
+
+	if r.Burst < 1 {
+		r.Burst = 1
+	}
+
+	return &tokenBucket{
+		rate:   r,
+		tokens: float64(r.Burst),
+		last:   now,
+	}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if b == nil {
+		return
+	}
+
+	b.tokens = math.Min(float64(b.rate.Burst), b.tokens+now.Sub(b.last).Seconds()*b.rate.PerSecond)
+	b.last = now
+}
+
+func (b *tokenBucket) available() bool {
+	return b == nil || b.tokens >= 1
+}
+
+func (b *tokenBucket) take() {
+	if b != nil {
+		b.tokens--
+	}
+}
+
+// wait returns how long it takes until a token is available.
+func (b *tokenBucket) wait() time.Duration {
+	if b.available() {
+		return 0
+	}
+
+	return time.Duration(math.Ceil((1 - b.tokens) / b.rate.PerSecond * float64(time.Second)))
+}
+
+// OrderScheduler hands out permits for order requests according to its
+// RateLimits.  Waiting requests are served by priority (see RequestType) and,
+// within the same priority, in FIFO order.
+type OrderScheduler struct {
+	mu      sync.Mutex
+	total   *tokenBucket
+	buckets [maxRequestTypes]*tokenBucket
+	queues  [maxRequestTypes][]*permit
+	// wake is when the pending dispatch timer fires, zero if there's none.
+	wake time.Time
+}
+
+type permit struct {
+	ready   chan struct{}
+	granted bool
+}
+
+func NewOrderScheduler(l RateLimits) *OrderScheduler {
+	now := time.Now()
+
+	s := &OrderScheduler{
+		mu:      sync.Mutex{},
+		total:   newTokenBucket(l.Total, now),
+		buckets: [maxRequestTypes]*tokenBucket{},
+		queues:  [maxRequestTypes][]*permit{},
+		wake:    time.Time{},
+	}
+	s.buckets[CancelRequest] = newTokenBucket(l.Cancel, now)
+	s.buckets[ModifyRequest] = newTokenBucket(l.Modify, now)
+	s.buckets[SubmitRequest] = newTokenBucket(l.Submit, now)
+
+	return s
+}
+
+// Wait blocks until a request of type t may be sent or ctx is done, in which
+// case ctx.Err() is returned.
+func (s *OrderScheduler) Wait(ctx context.Context, t RequestType) error {
+	p := &permit{
+		ready:   make(chan struct{}),
+		granted: false,
+	}
+
+	s.mu.Lock()
+	s.queues[t] = append(s.queues[t], p)
+	s.dispatch(time.Now())
+	s.mu.Unlock()
+
+	select {
+	case <-p.ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		// The permit may have been granted in the meantime, in which case the
+		// token is spent anyway.
+		if p.granted {
+			return nil
+		}
+
+		for i, q := range s.queues[t] {
+			if q == p {
+				s.queues[t] = append(s.queues[t][:i], s.queues[t][i+1:]...)
+
+				break
+			}
+		}
+
+		return ctx.Err()
+	}
+}
+
+// dispatch grants as many permits as possible.  A request waiting on the total
+// bucket blocks all requests of lower priority.  Must be called with s.mu held.
+func (s *OrderScheduler) dispatch(now time.Time) {
+	s.total.refill(now)
+
+	for t := range s.queues {
+		b := s.buckets[t]
+		b.refill(now)
+
+		for len(s.queues[t]) > 0 {
+			if !s.total.available() {
+				s.schedule(now)
+
+				return
+			}
+
+			if !b.available() {
+				break
+			}
+
+			s.total.take()
+			b.take()
+
+			p := s.queues[t][0]
+			s.queues[t] = s.queues[t][1:]
+			p.granted = true
+			close(p.ready)
+		}
+	}
+
+	s.schedule(now)
+}
+
+// schedule arranges for dispatch to be called once the first waiting request
+// may be granted.  Must be called with s.mu held.
+func (s *OrderScheduler) schedule(now time.Time) {
+	var (
+		next  time.Duration
+		found bool
+	)
+
+	for t, q := range s.queues {
+		if len(q) == 0 {
+			continue
+		}
+
+		d := s.total.wait()
+		if w := s.buckets[t].wait(); w > d {
+			d = w
+		}
+
+		if !found || d < next {
+			next, found = d, true
+		}
+	}
+
+	if !found {
+		return
+	}
+
+	wake := now.Add(next)
+	if !s.wake.IsZero() && !wake.Before(s.wake) {
+		return
+	}
+
+	s.wake = wake
+
+	time.AfterFunc(next, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if s.wake.Equal(wake) {
+			s.wake = time.Time{}
+		}
+
+		s.dispatch(time.Now())
+	})
+}
@@ -0,0 +1,81 @@
+package dola_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/numeusxyz/dola"
+)
+
+// nolint: exhaustivestruct
+func TestOrderScheduler_Priority(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx   = context.Background()
+		s     = dola.NewOrderScheduler(dola.RateLimits{Total: dola.Rate{PerSecond: 10, Burst: 1}})
+		mu    sync.Mutex
+		order []string
+		wg    sync.WaitGroup
+	)
+
+	// Spend the only token.
+	if err := s.Wait(ctx, dola.SubmitRequest); err != nil {
+		t.Fatal(err)
+	}
+
+	wait := func(name string, typ dola.RequestType) {
+		defer wg.Done()
+
+		if err := s.Wait(ctx, typ); err != nil {
+			t.Error(err)
+		}
+
+		mu.Lock()
+		order = append(order, name)
+		mu.Unlock()
+	}
+
+	wg.Add(3)
+
+	go wait("submit", dola.SubmitRequest)
+	time.Sleep(10 * time.Millisecond)
+
+	go wait("modify", dola.ModifyRequest)
+	time.Sleep(10 * time.Millisecond)
+
+	go wait("cancel", dola.CancelRequest)
+
+	wg.Wait()
+
+	if diff := cmp.Diff([]string{"cancel", "modify", "submit"}, order); diff != "" {
+		t.Error(diff)
+	}
+}
+
+// nolint: exhaustivestruct
+func TestOrderScheduler_Deadline(t *testing.T) {
+	t.Parallel()
+
+	s := dola.NewOrderScheduler(dola.RateLimits{Submit: dola.Rate{PerSecond: 1, Burst: 1}})
+
+	if err := s.Wait(context.Background(), dola.SubmitRequest); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := s.Wait(ctx, dola.SubmitRequest); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("have %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// Other request types have their own limits.
+	if err := s.Wait(ctx, dola.CancelRequest); err != nil {
+		t.Error(err)
+	}
+}
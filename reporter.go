@@ -28,6 +28,25 @@ const (
 	GetActiveOrdersMetric
 	GetActiveOrdersLatencyMetric
 	GetActiveOrdersErrorMetric
+	// Risk check metrics.
+	RiskRejectionMetric
+	// Kill switch metrics.
+	HaltMetric
+	// Rate limiter metrics.
+	RateLimitLatencyMetric
+	RateLimitTimeoutMetric
+	// Conditional order metrics.
+	ConditionalOrderErrorMetric
+	// Order group metrics.
+	OrderGroupOverfillMetric
+	// Get order info metrics.
+	GetOrderInfoMetric
+	GetOrderInfoLatencyMetric
+	GetOrderInfoErrorMetric
+	// Submission retry metrics.
+	SubmitRetryMetric
+	SubmitRecoveredMetric
+	SubmitRetryExhaustedMetric
 	// this should always be the last one.
 	MaxMetrics
 )
@@ -0,0 +1,199 @@
+package dola
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+)
+
+// +-------------+
+// | RetryPolicy |
+// +-------------+
+
+// retryLookupTimeout bounds the search for an order whose submission failed.
+const retryLookupTimeout = 10 * time.Second
+
+var (
+	// ErrTransient can be wrapped by exchange wrappers to mark errors worth
+	// retrying.
+	ErrTransient = errors.New("transient error")
+	// ErrSubmitOutcomeUnknown is returned when a submission failed ambiguously
+	// and the exchange couldn't be asked whether the order got placed.
+	ErrSubmitOutcomeUnknown = errors.New("unable to tell whether order got placed")
+)
+
+// RetryPolicy configures resubmission of orders.  Before every retry, and once
+// the retries are exhausted or the caller's context is done, the exchange's open
+// orders and order history are searched for the order's client order ID, so
+// that an order that did get placed isn't placed twice, nor reported as failed.
+// The search has a context of its own, bounded by retryLookupTimeout, so that it
+// also runs after the caller's context expired.  Orders submitted without a
+// client order ID get a random one.
+type RetryPolicy struct {
+	// Attempts is the retry budget per order.  Zero disables retries and the
+	// searches along with them.
+	Attempts int
+	// Backoff is the delay before the first retry, doubled for each next one.
+	Backoff time.Duration
+	// IsTransient classifies errors worth retrying.  Defaults to
+	// IsTransientError.
+	IsTransient func(error) bool
+}
+
+func (p RetryPolicy) transient(err error) bool {
+	if p.IsTransient != nil {
+		return p.IsTransient(err)
+	}
+
+	return IsTransientError(err)
+}
+
+// IsTransientError reports whether err is likely due to a timeout or a dropped
+// connection, i.e. an order may or may not have reached the exchange.
+func IsTransientError(err error) bool {
+	var netErr net.Error
+
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, ErrTransient),
+		errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNRESET):
+		return true
+	case errors.As(err, &netErr):
+		return netErr.Timeout()
+	default:
+		return false
+	}
+}
+
+// submitWithRetries submits x, retrying as configured by bot.retry.  Before
+// each retry, and once the retries are exhausted or ctx is done, the order is
+// looked up on the exchange.  ErrSubmitOutcomeUnknown is returned if that's not
+// possible.
+func (bot *Keep) submitWithRetries(ctx context.Context,
+	e exchange.IBotExchange,
+	entry orderEntry,
+	x *order.Submit) (order.SubmitResponse, error) {
+	since := time.Now()
+	resp, err := entry.SubmitOrder(ctx, x)
+
+	if err == nil || bot.retry.Attempts <= 0 || x.ClientOrderID == "" || !bot.retry.transient(err) {
+		return resp, err
+	}
+
+	backoff := bot.retry.Backoff
+
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+		}
+
+		backoff *= 2
+
+		found, lookupErr := bot.lookupOrder(entry, *x, since)
+		if lookupErr != nil {
+			return resp, fmt.Errorf("%w: %s: %v", ErrSubmitOutcomeUnknown, x.ClientOrderID, lookupErr)
+		}
+
+		if found != nil {
+			What(log.Info().Str("exchange", e.GetName()).Str("clientOrderID", x.ClientOrderID),
+				"order placed despite submission error")
+			bot.ReportEvent(SubmitRecoveredMetric, e.GetName())
+
+			return order.SubmitResponse{ // nolint: exhaustivestruct
+				IsOrderPlaced: true,
+				OrderID:       found.ID,
+			}, nil
+		}
+
+		// The order didn't get placed, and won't be anymore.
+		if ctx.Err() != nil {
+			return resp, err
+		}
+
+		if attempt > bot.retry.Attempts {
+			bot.ReportEvent(SubmitRetryExhaustedMetric, e.GetName())
+
+			return resp, err
+		}
+
+		if err := bot.throttleTrading(ctx, e, SubmitRequest); err != nil {
+			return resp, err
+		}
+
+		bot.ReportEvent(SubmitRetryMetric, e.GetName())
+
+		resp, err = entry.SubmitOrder(ctx, x)
+		if err == nil || !bot.retry.transient(err) {
+			return resp, err
+		}
+	}
+}
+
+// lookupOrder is like findOrder, but with a context of its own so that it can
+// run after the submission's context expired.
+func (bot *Keep) lookupOrder(entry orderEntry, x order.Submit, since time.Time) (*order.Detail, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), retryLookupTimeout)
+	defer cancel()
+
+	return bot.findOrder(ctx, entry, x, since)
+}
+
+// findOrder looks for an order with x's client order ID among the open orders
+// and the order history since the given time.
+func (bot *Keep) findOrder(ctx context.Context,
+	entry orderEntry,
+	x order.Submit,
+	since time.Time) (*order.Detail, error) {
+	request := order.GetOrdersRequest{
+		Type:      order.AnyType,
+		Side:      order.AnySide,
+		StartTime: time.Time{},
+		EndTime:   time.Time{},
+		OrderID:   "",
+		Pairs:     []currency.Pair{x.Pair},
+		AssetType: x.AssetType,
+	}
+
+	xs, err := entry.GetActiveOrders(ctx, &request)
+	if err != nil {
+		return nil, err
+	}
+
+	if found := findByClientOrderID(xs, x.ClientOrderID); found != nil {
+		return found, nil
+	}
+
+	// Allow for some clock skew.
+	request.StartTime = since.Add(-time.Minute)
+	request.EndTime = time.Now().Add(time.Minute)
+
+	if xs, err = entry.GetOrderHistory(ctx, &request); err != nil {
+		return nil, err
+	}
+
+	return findByClientOrderID(xs, x.ClientOrderID), nil
+}
+
+func findByClientOrderID(xs []order.Detail, clientOrderID string) *order.Detail {
+	for i := range xs {
+		if strings.EqualFold(xs[i].ClientOrderID, clientOrderID) {
+			return &xs[i]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,250 @@
+package dola_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/numeusxyz/dola"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+)
+
+var errTimeout = fmt.Errorf("%w: request timed out", dola.ErrTransient)
+
+// nolint: exhaustivestruct
+func retryOrder() order.Submit {
+	return order.Submit{
+		Amount:    1,
+		Price:     100,
+		Type:      order.Limit,
+		Side:      order.Buy,
+		Pair:      currency.NewPair(currency.BTC, currency.USDT),
+		AssetType: asset.Spot,
+	}
+}
+
+// nolint: exhaustivestruct
+func TestKeep_SubmitOrder_Retry(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k         dola.Keep
+		e         = newFakeExchange("fake")
+		clientIDs []string
+	)
+
+	k.SetRetryPolicy(dola.RetryPolicy{Attempts: 2})
+
+	e.reject = func(x order.Submit) error {
+		clientIDs = append(clientIDs, x.ClientOrderID)
+		if len(clientIDs) == 1 {
+			return errTimeout
+		}
+
+		return nil
+	}
+
+	resp, err := k.SubmitOrder(context.Background(), e, retryOrder())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(clientIDs) != 2 || clientIDs[0] == "" || clientIDs[0] != clientIDs[1] {
+		t.Errorf("unexpected client order IDs: %v", clientIDs)
+	}
+
+	if _, ok := k.GetOrderValue(e.GetName(), resp.OrderID); !ok {
+		t.Error("order not in registry")
+	}
+}
+
+// nolint: exhaustivestruct
+func TestKeep_SubmitOrder_RetryRecovers(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k dola.Keep
+		e = newFakeExchange("fake")
+	)
+
+	k.SetRetryPolicy(dola.RetryPolicy{Attempts: 2})
+
+	// The order reaches the exchange, but the response gets lost.  The
+	// fake's lock is held while reject runs.
+	e.reject = func(x order.Submit) error {
+		e.history = append(e.history, order.Detail{ID: "placed", ClientOrderID: x.ClientOrderID})
+
+		return errTimeout
+	}
+
+	resp, err := k.SubmitOrder(context.Background(), e, retryOrder())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.OrderID != "placed" || len(e.history) != 1 {
+		t.Errorf("have order %q and %d placed, want a single one", resp.OrderID, len(e.history))
+	}
+
+	if _, ok := k.GetOrderValue(e.GetName(), "placed"); !ok {
+		t.Error("order not in registry")
+	}
+}
+
+// nolint: exhaustivestruct
+func TestKeep_SubmitOrder_NoRetry(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k        dola.Keep
+		e        = newFakeExchange("fake")
+		attempts int
+		errFunds = errors.New("insufficient funds")
+	)
+
+	k.SetRetryPolicy(dola.RetryPolicy{Attempts: 2})
+
+	e.reject = func(x order.Submit) error {
+		attempts++
+
+		return errFunds
+	}
+
+	if _, err := k.SubmitOrder(context.Background(), e, retryOrder()); !errors.Is(err, errFunds) {
+		t.Errorf("have %v, want %v", err, errFunds)
+	}
+
+	if attempts != 1 {
+		t.Errorf("have %d attempts, want 1", attempts)
+	}
+
+	if dola.IsTransientError(errFunds) || !dola.IsTransientError(context.DeadlineExceeded) {
+		t.Error("misclassified errors")
+	}
+}
+
+// nolint: exhaustivestruct
+func TestKeep_SubmitOrder_RetryExhausted(t *testing.T) {
+	t.Parallel()
+
+	t.Run("placed", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			k        dola.Keep
+			e        = newFakeExchange("fake")
+			attempts int
+		)
+
+		k.SetRetryPolicy(dola.RetryPolicy{Attempts: 2})
+
+		// Only the last attempt reaches the exchange.
+		e.reject = func(x order.Submit) error {
+			if attempts++; attempts == 3 {
+				e.history = append(e.history, order.Detail{ID: "placed", ClientOrderID: x.ClientOrderID})
+			}
+
+			return errTimeout
+		}
+
+		resp, err := k.SubmitOrder(context.Background(), e, retryOrder())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if attempts != 3 || resp.OrderID != "placed" {
+			t.Errorf("have order %q after %d attempts, want \"placed\" after 3", resp.OrderID, attempts)
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			k        dola.Keep
+			e        = newFakeExchange("fake")
+			attempts int
+		)
+
+		k.SetRetryPolicy(dola.RetryPolicy{Attempts: 1})
+
+		// The lookup after the last attempt fails.
+		e.reject = func(x order.Submit) error {
+			if attempts++; attempts == 2 {
+				e.ordersErr = errTimeout
+			}
+
+			return errTimeout
+		}
+
+		if _, err := k.SubmitOrder(context.Background(), e, retryOrder()); !errors.Is(err, dola.ErrSubmitOutcomeUnknown) {
+			t.Errorf("have %v, want %v", err, dola.ErrSubmitOutcomeUnknown)
+		}
+	})
+}
+
+// nolint: exhaustivestruct
+func TestKeep_SubmitOrder_RetryDeadline(t *testing.T) {
+	t.Parallel()
+
+	for _, placed := range []bool{true, false} {
+		var (
+			k           dola.Keep
+			e           = newFakeExchange("fake")
+			attempts    int
+			ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+		)
+
+		k.SetRetryPolicy(dola.RetryPolicy{Attempts: 2, Backoff: time.Hour})
+
+		// The request outlives the caller's deadline.
+		e.reject = func(x order.Submit) error {
+			attempts++
+			if placed {
+				e.history = append(e.history, order.Detail{ID: "placed", ClientOrderID: x.ClientOrderID})
+			}
+
+			time.Sleep(30 * time.Millisecond)
+
+			return context.DeadlineExceeded
+		}
+
+		resp, err := k.SubmitOrder(ctx, e, retryOrder())
+
+		cancel()
+
+		switch {
+		case attempts != 1:
+			t.Errorf("placed %v: have %d attempts, want 1", placed, attempts)
+		case placed && (err != nil || resp.OrderID != "placed"):
+			t.Errorf("placed %v: have (%q, %v), want the placed order", placed, resp.OrderID, err)
+		case !placed && !errors.Is(err, context.DeadlineExceeded):
+			t.Errorf("placed %v: have %v, want %v", placed, err, context.DeadlineExceeded)
+		}
+	}
+}
+
+// nolint: exhaustivestruct
+func TestKeep_SubmitOrder_NoRetryPolicy(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k dola.Keep
+		e = newFakeExchange("fake")
+		x = retryOrder()
+	)
+
+	// Looking the order up would fail.
+	e.ordersErr = errTimeout
+	e.reject = func(order.Submit) error { return errTimeout }
+	x.ClientOrderID = "client"
+
+	if _, err := k.SubmitOrder(context.Background(), e, x); !errors.Is(err, errTimeout) ||
+		errors.Is(err, dola.ErrSubmitOutcomeUnknown) {
+		t.Errorf("have %v, want %v", err, errTimeout)
+	}
+}
@@ -0,0 +1,456 @@
+package dola
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+)
+
+// +-----------+
+// | RiskCheck |
+// +-----------+
+
+var (
+	ErrMaxNotional       = errors.New("order notional exceeds limit")
+	ErrMaxOpenOrders     = errors.New("too many open orders for pair")
+	ErrMaxPosition       = errors.New("position would exceed limit")
+	ErrPriceBand         = errors.New("order price outside of band")
+	ErrOrderRate         = errors.New("order rate exceeds limit")
+	ErrNoReferencePrice  = errors.New("no reference price for pair")
+	ErrUnknownOrderPrice = errors.New("order price unknown")
+)
+
+// RiskError is returned by Keep when a RiskCheck rejects an order.  Use
+// errors.Is to match the reason, e.g. ErrMaxNotional.
+type RiskError struct {
+	Check string
+	Err   error
+}
+
+func (e *RiskError) Error() string {
+	return fmt.Sprintf("risk check %s rejected order: %v", e.Check, e.Err)
+}
+
+func (e *RiskError) Unwrap() error {
+	return e.Err
+}
+
+// RiskCheck is run before every order submission and modification.  For
+// modifications, x is the original submission updated with the modified
+// fields, and x.ID is set to the ID of the order being modified.
+type RiskCheck interface {
+	Name() string
+	Check(k *Keep, e exchange.IBotExchange, x order.Submit) error
+}
+
+// RiskRecorder is implemented by risk checks that keep track of accepted
+// orders, e.g. OrderRateCheck.  Record is called once all checks have passed,
+// so that orders rejected by a later check aren't accounted for.  It may still
+// reject the order, e.g. if a concurrent one used up the budget meanwhile.
+type RiskRecorder interface {
+	Record(k *Keep, e exchange.IBotExchange, x order.Submit) error
+}
+
+// RiskReleaser is implemented by RiskRecorders that hold on to what they
+// recorded only while the order is in flight, e.g. MaxOpenOrdersCheck.  Release
+// is called once the submission failed or the order got stored in the registry.
+type RiskReleaser interface {
+	Release(k *Keep, e exchange.IBotExchange, x order.Submit)
+}
+
+// checkRisk runs all registered risk checks and stops at the first rejection.
+// Once all of them pass, the order is recorded by those implementing
+// RiskRecorder.  The returned function releases the order from the
+// RiskReleasers and has to be called once the submission is over.
+func (bot *Keep) checkRisk(e exchange.IBotExchange, x order.Submit) (func(), error) {
+	for _, c := range bot.risk {
+		if err := c.Check(bot, e, x); err != nil {
+			return func() {}, bot.rejectRisk(e, c, err)
+		}
+	}
+
+	var recorded []RiskReleaser
+
+	release := func() {
+		for _, r := range recorded {
+			r.Release(bot, e, x)
+		}
+	}
+
+	for _, c := range bot.risk {
+		r, ok := c.(RiskRecorder)
+		if !ok {
+			continue
+		}
+
+		if err := r.Record(bot, e, x); err != nil {
+			release()
+
+			return func() {}, bot.rejectRisk(e, c, err)
+		}
+
+		if r, ok := c.(RiskReleaser); ok {
+			recorded = append(recorded, r)
+		}
+	}
+
+	return release, nil
+}
+
+func (bot *Keep) rejectRisk(e exchange.IBotExchange, c RiskCheck, err error) error {
+	bot.ReportEvent(RiskRejectionMetric, e.GetName(), c.Name())
+
+	return &RiskError{
+		Check: c.Name(),
+		Err:   err,
+	}
+}
+
+// orderPrice returns the limit price of x or, for market orders, the last
+// seen price of its pair.
+func orderPrice(k *Keep, e exchange.IBotExchange, x order.Submit) (float64, error) {
+	if x.Price > 0 {
+		return x.Price, nil
+	}
+
+	if err := checkTickerFresh(k, e, x); err != nil {
+		return 0, err
+	}
+
+	if price, ok := k.lastPrice(e.GetName(), x.AssetType, x.Pair); ok {
+		return price, nil
+	}
+
+	return 0, ErrUnknownOrderPrice
+}
+
+// checkTickerFresh returns ErrNoReferencePrice if the last seen ticker of x's
+// pair is stale (see Keep.LastTicker).
+func checkTickerFresh(k *Keep, e exchange.IBotExchange, x order.Submit) error {
+	if t, ok := k.LastTicker(e.GetName(), x.AssetType, x.Pair); ok && t.Stale {
+		return fmt.Errorf("%w: ticker received at %s is stale", ErrNoReferencePrice, t.Received)
+	}
+
+	return nil
+}
+
+// +------------------+
+// | MaxNotionalCheck |
+// +------------------+
+
+// MaxNotionalCheck rejects orders whose price times amount exceeds Limit.
+type MaxNotionalCheck struct {
+	Limit float64
+}
+
+func (c MaxNotionalCheck) Name() string {
+	return "max_notional"
+}
+
+func (c MaxNotionalCheck) Check(k *Keep, e exchange.IBotExchange, x order.Submit) error {
+	price, err := orderPrice(k, e, x)
+	if err != nil {
+		return err
+	}
+
+	if notional := price * x.Amount; notional > c.Limit {
+		return fmt.Errorf("%w: %f > %f", ErrMaxNotional, notional, c.Limit)
+	}
+
+	return nil
+}
+
+// +--------------------+
+// | MaxOpenOrdersCheck |
+// +--------------------+
+
+// DefaultOpenOrderExpiry is used by MaxOpenOrdersCheck when Expiry is zero.
+const DefaultOpenOrderExpiry = time.Minute
+
+// MaxOpenOrdersCheck limits the number of open orders per exchange, asset and
+// pair.  Open orders are counted from the order registry, so it relies on
+// order updates being streamed through Strategy.OnOrder.  Orders still being
+// submitted hold a slot from the moment they pass all risk checks until they
+// are stored in the registry or fail, so that concurrent submissions, e.g. of a
+// batch, can't exceed the limit.
+//
+// An order that never gets an update, e.g. because it was missed while
+// reconnecting, is counted only for Expiry after its submission.  Orders that
+// got an update count until they are reported closed.
+type MaxOpenOrdersCheck struct {
+	Limit  int
+	Expiry time.Duration
+}
+
+func (c MaxOpenOrdersCheck) Name() string {
+	return "max_open_orders"
+}
+
+func (c MaxOpenOrdersCheck) Check(k *Keep, e exchange.IBotExchange, x order.Submit) error {
+	// Modifications don't open new orders.
+	if x.ID != "" {
+		return nil
+	}
+
+	k.openOrders.mu.Lock()
+	defer k.openOrders.mu.Unlock()
+
+	return c.check(k, e, x)
+}
+
+// Record reserves a slot for x until Release.
+func (c MaxOpenOrdersCheck) Record(k *Keep, e exchange.IBotExchange, x order.Submit) error {
+	if x.ID != "" {
+		return nil
+	}
+
+	k.openOrders.mu.Lock()
+	defer k.openOrders.mu.Unlock()
+
+	if err := c.check(k, e, x); err != nil {
+		return err
+	}
+
+	if k.openOrders.reserved == nil {
+		k.openOrders.reserved = make(map[openOrdersKey]int)
+	}
+
+	k.openOrders.reserved[c.key(e, x)]++
+
+	return nil
+}
+
+func (c MaxOpenOrdersCheck) Release(k *Keep, e exchange.IBotExchange, x order.Submit) {
+	if x.ID != "" {
+		return
+	}
+
+	k.openOrders.mu.Lock()
+	defer k.openOrders.mu.Unlock()
+
+	key := c.key(e, x)
+	if k.openOrders.reserved[key]--; k.openOrders.reserved[key] <= 0 {
+		delete(k.openOrders.reserved, key)
+	}
+}
+
+// check counts the open and reserved orders.  k.openOrders.mu must be held.
+func (c MaxOpenOrdersCheck) check(k *Keep, e exchange.IBotExchange, x order.Submit) error {
+	expiry := c.Expiry
+	if expiry <= 0 {
+		expiry = DefaultOpenOrderExpiry
+	}
+
+	n := k.openOrders.reserved[c.key(e, x)]
+	now := time.Now()
+
+	k.registry.Range(func(key OrderKey, value OrderValue) bool {
+		if key.ExchangeName == e.GetName() &&
+			!value.Synthetic &&
+			value.Submit.AssetType == x.AssetType &&
+			value.Submit.Pair.Equal(x.Pair) &&
+			value.IsOpen() &&
+			(value.Detail.Status != "" || now.Sub(value.Stored) < expiry) {
+			n++
+		}
+
+		return true
+	})
+
+	if n >= c.Limit {
+		return fmt.Errorf("%w: %d >= %d", ErrMaxOpenOrders, n, c.Limit)
+	}
+
+	return nil
+}
+
+func (c MaxOpenOrdersCheck) key(e exchange.IBotExchange, x order.Submit) openOrdersKey {
+	return openOrdersKey{
+		check:  c,
+		market: newMarketKey(e.GetName(), x.AssetType, x.Pair),
+	}
+}
+
+// openOrdersKey identifies the slots reserved by a MaxOpenOrdersCheck for a
+// market.
+type openOrdersKey struct {
+	check  MaxOpenOrdersCheck
+	market marketKey
+}
+
+// openOrderReservations counts the slots reserved for orders being submitted.
+type openOrderReservations struct {
+	mu       sync.Mutex
+	reserved map[openOrdersKey]int
+}
+
+// +------------------+
+// | MaxPositionCheck |
+// +------------------+
+
+// MaxPositionCheck rejects orders that would grow the holdings of Currency
+// beyond Limit in absolute terms.  Orders that reduce the position are always
+// accepted.  Requires Keep to be configured with balances support.
+type MaxPositionCheck struct {
+	Currency currency.Code
+	Limit    float64
+}
+
+func (c MaxPositionCheck) Name() string {
+	return "max_position"
+}
+
+func (c MaxPositionCheck) Check(k *Keep, e exchange.IBotExchange, x order.Submit) error {
+	var delta float64
+
+	switch {
+	case x.Pair.Base.Match(c.Currency):
+		delta = x.Amount
+	case x.Pair.Quote.Match(c.Currency):
+		price, err := orderPrice(k, e, x)
+		if err != nil {
+			return err
+		}
+
+		delta = -price * x.Amount
+	default:
+		return nil
+	}
+
+	if x.Side == order.Sell || x.Side == order.Ask {
+		delta = -delta
+	}
+
+	holdings, err := Holdings(k, e.GetName())
+	if err != nil {
+		return err
+	}
+
+	position := 0.0
+
+	for _, account := range holdings.Accounts {
+		if balance, ok := account.Balances[x.AssetType][c.Currency]; ok {
+			position += balance.TotalValue
+		}
+	}
+
+	after := position + delta
+	if math.Abs(after) > c.Limit && math.Abs(after) > math.Abs(position) {
+		return fmt.Errorf("%w: %s %f > %f", ErrMaxPosition, c.Currency, math.Abs(after), c.Limit)
+	}
+
+	return nil
+}
+
+// +----------------+
+// | PriceBandCheck |
+// +----------------+
+
+// PriceBandCheck rejects limit orders whose price deviates from the last seen
+// ticker price by more than Fraction (e.g. 0.05 for 5%), or if that ticker is
+// missing or stale.  Market orders are not checked.
+type PriceBandCheck struct {
+	Fraction float64
+}
+
+func (c PriceBandCheck) Name() string {
+	return "price_band"
+}
+
+func (c PriceBandCheck) Check(k *Keep, e exchange.IBotExchange, x order.Submit) error {
+	if x.Price <= 0 {
+		return nil
+	}
+
+	if err := checkTickerFresh(k, e, x); err != nil {
+		return err
+	}
+
+	last, ok := k.lastPrice(e.GetName(), x.AssetType, x.Pair)
+	if !ok {
+		return ErrNoReferencePrice
+	}
+
+	if deviation := math.Abs(x.Price-last) / last; deviation > c.Fraction {
+		return fmt.Errorf("%w: price %f, last %f", ErrPriceBand, x.Price, last)
+	}
+
+	return nil
+}
+
+// +----------------+
+// | OrderRateCheck |
+// +----------------+
+
+// OrderRateCheck limits the number of orders submitted or modified per exchange
+// within any one-second window.  Only orders that pass all risk checks count
+// towards the limit.
+type OrderRateCheck struct {
+	Limit int
+
+	mu    sync.Mutex
+	times map[string][]time.Time
+}
+
+func NewOrderRateCheck(limit int) *OrderRateCheck {
+	return &OrderRateCheck{
+		Limit: limit,
+		mu:    sync.Mutex{},
+		times: make(map[string][]time.Time),
+	}
+}
+
+func (c *OrderRateCheck) Name() string {
+	return "order_rate"
+}
+
+func (c *OrderRateCheck) Check(k *Keep, e exchange.IBotExchange, x order.Submit) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, err := c.window(strings.ToLower(e.GetName()), time.Now())
+
+	return err
+}
+
+// Record counts an order that passed all risk checks.
+func (c *OrderRateCheck) Record(k *Keep, e exchange.IBotExchange, x order.Submit) error {
+	key := strings.ToLower(e.GetName())
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	xs, err := c.window(key, now)
+	if err != nil {
+		return err
+	}
+
+	c.times[key] = append(xs, now)
+
+	return nil
+}
+
+// window drops the timestamps of key that have fallen out of the window ending
+// at now and returns the remaining ones, or an error if there is no room left.
+func (c *OrderRateCheck) window(key string, now time.Time) ([]time.Time, error) {
+	xs := c.times[key]
+	for len(xs) > 0 && now.Sub(xs[0]) >= time.Second {
+		xs = xs[1:]
+	}
+
+	c.times[key] = xs
+
+	if len(xs) >= c.Limit {
+		return xs, fmt.Errorf("%w: %d orders/s", ErrOrderRate, c.Limit)
+	}
+
+	return xs, nil
+}
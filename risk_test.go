@@ -0,0 +1,251 @@
+package dola_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/numeusxyz/dola"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/ticker"
+)
+
+// nolint: exhaustivestruct
+func TestRiskChecks(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k    dola.Keep
+		e    = newFakeExchange("fake")
+		pair = currency.NewPair(currency.BTC, currency.USDT)
+	)
+
+	k.OnPrice(e, ticker.Price{Last: 100, Pair: pair, AssetType: asset.Spot})
+
+	submit := func(price, amount float64) order.Submit {
+		return order.Submit{
+			Price:     price,
+			Amount:    amount,
+			Side:      order.Buy,
+			Pair:      pair,
+			AssetType: asset.Spot,
+		}
+	}
+
+	for _, tc := range []struct {
+		name  string
+		check dola.RiskCheck
+		x     order.Submit
+		want  error
+	}{
+		{"notional ok", dola.MaxNotionalCheck{Limit: 1000}, submit(100, 10), nil},
+		{"notional limit", dola.MaxNotionalCheck{Limit: 1000}, submit(100, 11), dola.ErrMaxNotional},
+		{"notional market", dola.MaxNotionalCheck{Limit: 1000}, submit(0, 11), dola.ErrMaxNotional},
+		{"band ok", dola.PriceBandCheck{Fraction: 0.1}, submit(95, 1), nil},
+		{"band limit", dola.PriceBandCheck{Fraction: 0.1}, submit(89, 1), dola.ErrPriceBand},
+		{"band market", dola.PriceBandCheck{Fraction: 0.1}, submit(0, 1), nil},
+	} {
+		if err := tc.check.Check(&k, e, tc.x); !errors.Is(err, tc.want) {
+			t.Errorf("%s: have %v, want %v", tc.name, err, tc.want)
+		}
+	}
+}
+
+// nolint: exhaustivestruct
+func TestMaxOpenOrdersCheck(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k     dola.Keep
+		e     = newFakeExchange("fake")
+		check = dola.MaxOpenOrdersCheck{Limit: 2}
+		x     = order.Submit{
+			Pair:      currency.NewPair(currency.BTC, currency.USDT),
+			AssetType: asset.Spot,
+		}
+	)
+
+	for i := 0; i < 2; i++ {
+		if err := check.Check(&k, e, x); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := k.SubmitOrder(context.Background(), e, x); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := check.Check(&k, e, x); !errors.Is(err, dola.ErrMaxOpenOrders) {
+		t.Errorf("have %v, want %v", err, dola.ErrMaxOpenOrders)
+	}
+
+	// Once an order gets cancelled, there is room for one more.
+	k.OnOrder(e, order.Detail{ID: "1", Status: order.Cancelled})
+
+	if err := check.Check(&k, e, x); err != nil {
+		t.Error(err)
+	}
+}
+
+// nolint: exhaustivestruct
+func TestMaxOpenOrdersCheck_Expiry(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k     dola.Keep
+		e     = newFakeExchange("fake")
+		check = dola.MaxOpenOrdersCheck{Limit: 1, Expiry: 50 * time.Millisecond}
+		x     = order.Submit{
+			Pair:      currency.NewPair(currency.BTC, currency.USDT),
+			AssetType: asset.Spot,
+		}
+	)
+
+	if _, err := k.SubmitOrder(context.Background(), e, x); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := check.Check(&k, e, x); !errors.Is(err, dola.ErrMaxOpenOrders) {
+		t.Errorf("have %v, want %v", err, dola.ErrMaxOpenOrders)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	// The order never got an update, so it no longer counts.
+	if err := check.Check(&k, e, x); err != nil {
+		t.Error(err)
+	}
+
+	// Orders known to be open count regardless of their age.
+	k.OnOrder(e, order.Detail{ID: "1", Status: order.New, Pair: x.Pair, AssetType: x.AssetType})
+
+	if err := check.Check(&k, e, x); !errors.Is(err, dola.ErrMaxOpenOrders) {
+		t.Errorf("have %v, want %v", err, dola.ErrMaxOpenOrders)
+	}
+}
+
+func TestOrderRateCheck(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k     dola.Keep
+		e     = newFakeExchange("fake")
+		check = dola.NewOrderRateCheck(3)
+	)
+
+	// Checking alone doesn't use up the budget.
+	for i := 0; i < 5; i++ {
+		if err := check.Check(&k, e, order.Submit{}); err != nil { // nolint: exhaustivestruct
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := check.Record(&k, e, order.Submit{}); err != nil { // nolint: exhaustivestruct
+			t.Fatal(err)
+		}
+	}
+
+	if err := check.Check(&k, e, order.Submit{}); !errors.Is(err, dola.ErrOrderRate) { // nolint: exhaustivestruct
+		t.Errorf("have %v, want %v", err, dola.ErrOrderRate)
+	}
+
+	if err := check.Record(&k, e, order.Submit{}); !errors.Is(err, dola.ErrOrderRate) { // nolint: exhaustivestruct
+		t.Errorf("have %v, want %v", err, dola.ErrOrderRate)
+	}
+}
+
+// nolint: exhaustivestruct
+func TestOrderRateCheck_RejectedDownstream(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k    dola.Keep
+		e    = newFakeExchange("fake")
+		pair = currency.NewPair(currency.BTC, currency.USDT)
+		ctx  = context.Background()
+	)
+
+	k.SetRiskChecks(dola.NewOrderRateCheck(1), dola.MaxNotionalCheck{Limit: 1000})
+
+	if _, err := k.SubmitOrder(ctx, e, order.Submit{Price: 100, Amount: 20, Pair: pair}); !errors.Is(err, dola.ErrMaxNotional) {
+		t.Fatalf("have %v, want %v", err, dola.ErrMaxNotional)
+	}
+
+	if _, err := k.SubmitOrder(ctx, e, order.Submit{Price: 100, Amount: 1, Pair: pair}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := k.SubmitOrder(ctx, e, order.Submit{Price: 100, Amount: 1, Pair: pair}); !errors.Is(err, dola.ErrOrderRate) {
+		t.Errorf("have %v, want %v", err, dola.ErrOrderRate)
+	}
+}
+
+// nolint: exhaustivestruct
+func TestMaxOpenOrdersCheck_Reservations(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k     dola.Keep
+		e     = newFakeExchange("fake")
+		check = dola.MaxOpenOrdersCheck{Limit: 1}
+		x     = order.Submit{
+			Pair:      currency.NewPair(currency.BTC, currency.USDT),
+			AssetType: asset.Spot,
+		}
+		errDown = errors.New("down")
+	)
+
+	// An order in flight holds its slot.
+	if err := check.Record(&k, e, x); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := check.Check(&k, e, x); !errors.Is(err, dola.ErrMaxOpenOrders) {
+		t.Errorf("have %v, want %v", err, dola.ErrMaxOpenOrders)
+	}
+
+	check.Release(&k, e, x)
+
+	// A failed submission gives its slot back.
+	k.SetRiskChecks(check)
+	e.reject = func(order.Submit) error { return errDown }
+
+	if _, err := k.SubmitOrder(context.Background(), e, x); !errors.Is(err, errDown) {
+		t.Fatalf("have %v, want %v", err, errDown)
+	}
+
+	if err := check.Check(&k, e, x); err != nil {
+		t.Error(err)
+	}
+}
+
+// nolint: exhaustivestruct
+func TestRiskChecks_StaleTicker(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k    dola.Keep
+		e    = newFakeExchange("fake")
+		pair = currency.NewPair(currency.BTC, currency.USDT)
+	)
+
+	k.SetTickerStaleness(time.Millisecond)
+	k.OnPrice(e, ticker.Price{Last: 100, Pair: pair, AssetType: asset.Spot})
+	time.Sleep(5 * time.Millisecond)
+
+	for _, tc := range []struct {
+		check dola.RiskCheck
+		x     order.Submit
+	}{
+		{dola.PriceBandCheck{Fraction: 0.1}, order.Submit{Price: 100, Amount: 1, Pair: pair, AssetType: asset.Spot}},
+		{dola.MaxNotionalCheck{Limit: 1000}, order.Submit{Amount: 1, Pair: pair, AssetType: asset.Spot}},
+	} {
+		if err := tc.check.Check(&k, e, tc.x); !errors.Is(err, dola.ErrNoReferencePrice) {
+			t.Errorf("%s: have %v, want %v", tc.check.Name(), err, dola.ErrNoReferencePrice)
+		}
+	}
+}
@@ -0,0 +1,400 @@
+package dola
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+)
+
+// +--------+
+// | Router |
+// +--------+
+
+var ErrNoLiquidity = errors.New("no liquidity to route the order to")
+
+// RouteRequest is a parent order to be split across exchanges.
+type RouteRequest struct {
+	Pair      currency.Pair
+	AssetType asset.Item
+	Side      order.Side
+	Amount    float64
+	// LimitPrice is the worst price liquidity is taken at.  Zero means no
+	// limit.
+	LimitPrice float64
+	// Exchanges to route to.  Defaults to all exchanges Keep manages.
+	Exchanges []exchange.IBotExchange
+}
+
+// RouteLeg is the part of a parent order routed to a single exchange.
+type RouteLeg struct {
+	Exchange string
+	Amount   float64
+	// Price is the worst book level the leg is expected to take, and the
+	// limit price of its immediate-or-cancel order.
+	Price float64
+	// ExpectedPrice is the expected average price, fees included.
+	ExpectedPrice float64
+	// FeeRate is the taker fee as a fraction of the notional.
+	FeeRate float64
+
+	exchange exchange.IBotExchange
+}
+
+// Router splits orders across exchanges by walking their streamed books (see
+// Keep.OrderBook), best price after taker fees first, within the balances
+// available on each exchange (if Keep is configured with balances, see
+// KeepBuilder.Balances).
+type Router struct{}
+
+func NewRouter() *Router {
+	return &Router{}
+}
+
+// level is a book level of a single exchange.
+type level struct {
+	exchange  int
+	price     float64
+	amount    float64
+	effective float64
+}
+
+// Plan splits req across exchanges without submitting anything.  If there isn't
+// enough liquidity, the legs add up to less than req.Amount.
+func (r *Router) Plan(ctx context.Context, k *Keep, req RouteRequest) ([]RouteLeg, error) {
+	exchanges := req.Exchanges
+	if len(exchanges) == 0 {
+		exchanges = k.GetExchanges()
+	}
+
+	buy := isBuy(req.Side)
+
+	var (
+		levels  []level
+		legs    = make([]RouteLeg, len(exchanges))
+		budgets = make([]float64, len(exchanges))
+	)
+
+	for i, e := range exchanges {
+		book, ok := k.OrderBook(e.GetName(), req.AssetType, req.Pair)
+		if !ok {
+			continue
+		}
+
+		side := book.Bids
+		if buy {
+			side = book.Asks
+		}
+
+		if len(side) == 0 {
+			continue
+		}
+
+		fee, err := k.TakerFeeRate(ctx, e, req.AssetType, req.Pair, side[0].Price)
+		if err != nil {
+			What(log.Warn().Err(err).Str("exchange", e.GetName()), "router: unable to get taker fee, assuming none")
+		}
+
+		legs[i] = RouteLeg{Exchange: e.GetName(), Amount: 0, Price: 0, ExpectedPrice: 0, FeeRate: fee, exchange: e}
+		budgets[i] = availableBalance(k, e, req)
+
+		for _, x := range side {
+			if req.LimitPrice > 0 && ((buy && x.Price > req.LimitPrice) || (!buy && x.Price < req.LimitPrice)) {
+				break
+			}
+
+			effective := x.Price * (1 - fee)
+			if buy {
+				effective = x.Price * (1 + fee)
+			}
+
+			levels = append(levels, level{exchange: i, price: x.Price, amount: x.Amount, effective: effective})
+		}
+	}
+
+	// Best levels first.  Per exchange, levels stay in book order.
+	sort.SliceStable(levels, func(i, j int) bool {
+		if buy {
+			return levels[i].effective < levels[j].effective
+		}
+
+		return levels[i].effective > levels[j].effective
+	})
+
+	remaining := req.Amount
+
+	for _, x := range levels {
+		if remaining <= 0 {
+			break
+		}
+
+		amount := math.Min(x.amount, remaining)
+
+		// Budgets are in the quote currency for buys and in the base currency
+		// for sells.
+		if buy {
+			amount = math.Min(amount, budgets[x.exchange]/x.effective)
+			budgets[x.exchange] -= amount * x.effective
+		} else {
+			amount = math.Min(amount, budgets[x.exchange])
+			budgets[x.exchange] -= amount
+		}
+
+		if amount <= 0 {
+			continue
+		}
+
+		leg := &legs[x.exchange]
+		leg.ExpectedPrice = (leg.ExpectedPrice*leg.Amount + x.effective*amount) / (leg.Amount + amount)
+		leg.Amount += amount
+		leg.Price = x.price
+		remaining -= amount
+	}
+
+	var planned []RouteLeg
+
+	for _, leg := range legs {
+		if leg.Amount > 0 {
+			planned = append(planned, leg)
+		}
+	}
+
+	if len(planned) == 0 {
+		return nil, ErrNoLiquidity
+	}
+
+	return planned, nil
+}
+
+// Route plans req and submits the legs as immediate-or-cancel limit orders.  The
+// returned report aggregates their fills as they come in.
+func (r *Router) Route(ctx context.Context, k *Keep, req RouteRequest) (*RouteReport, error) {
+	legs, err := r.Plan(ctx, k, req)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &RouteReport{ // nolint: exhaustivestruct
+		Request: req,
+		legs:    make([]RouteResult, len(legs)),
+		done:    make(chan struct{}),
+	}
+
+	for i, leg := range legs {
+		report.legs[i].RouteLeg = leg
+	}
+
+	var wg sync.WaitGroup
+
+	for i := range legs {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			report.submit(ctx, k, i)
+		}(i)
+	}
+
+	wg.Wait()
+
+	return report, nil
+}
+
+// availableBalance returns the free balance of what a buy spends (the quote
+// currency) or a sell sells (the base currency).  Without balances support the
+// balance is unlimited.
+func availableBalance(k *Keep, e exchange.IBotExchange, req RouteRequest) float64 {
+	holdings, err := Holdings(k, e.GetName())
+	if errors.Is(err, ErrNeedBalancesStrategy) {
+		return math.Inf(1)
+	}
+
+	if err != nil {
+		return 0
+	}
+
+	code := req.Pair.Base
+	if isBuy(req.Side) {
+		code = req.Pair.Quote
+	}
+
+	free := 0.0
+
+	for _, account := range holdings.Accounts {
+		if balance, ok := account.Balances[req.AssetType][code]; ok {
+			free += balance.TotalValue - balance.Hold
+		}
+	}
+
+	return free
+}
+
+// +-------------+
+// | RouteReport |
+// +-------------+
+
+// RouteResult is the outcome of a single leg.
+type RouteResult struct {
+	RouteLeg
+	OrderID      string
+	Executed     float64
+	AveragePrice float64
+	Err          error
+	closed       bool
+}
+
+// RouteReport aggregates the fills of a routed order.  Done is closed once all
+// legs are.
+type RouteReport struct {
+	Request RouteRequest
+
+	mu   sync.Mutex
+	legs []RouteResult
+	done chan struct{}
+}
+
+// routeLeg is the UserData of a single leg's order.
+type routeLeg struct {
+	report *RouteReport
+	index  int
+}
+
+func (r *RouteReport) submit(ctx context.Context, k *Keep, i int) {
+	r.mu.Lock()
+	leg := r.legs[i].RouteLeg
+	r.mu.Unlock()
+
+	// nolint: exhaustivestruct
+	resp, err := k.SubmitOrderUD(ctx, leg.exchange, order.Submit{
+		ImmediateOrCancel: true,
+		Price:             leg.Price,
+		Amount:            leg.Amount,
+		Type:              order.Limit,
+		Side:              r.Request.Side,
+		AssetType:         r.Request.AssetType,
+		Pair:              r.Request.Pair,
+	}, &routeLeg{report: r, index: i})
+
+	r.mu.Lock()
+	r.legs[i].OrderID = resp.OrderID
+	r.mu.Unlock()
+
+	if err != nil {
+		r.update(i, nil, err)
+	}
+}
+
+// Legs returns a snapshot of all legs.
+func (r *RouteReport) Legs() []RouteResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]RouteResult{}, r.legs...)
+}
+
+// Executed returns the amount executed across all legs.
+func (r *RouteReport) Executed() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	executed, _ := r.totals()
+
+	return executed
+}
+
+// AveragePrice returns the average execution price across all legs.
+func (r *RouteReport) AveragePrice() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	executed, cost := r.totals()
+	if executed == 0 {
+		return 0
+	}
+
+	return cost / executed
+}
+
+func (r *RouteReport) Done() <-chan struct{} {
+	return r.done
+}
+
+func (r *RouteReport) totals() (executed, cost float64) {
+	for _, leg := range r.legs {
+		executed += leg.Executed
+		cost += leg.Executed * leg.AveragePrice
+	}
+
+	return executed, cost
+}
+
+// update records a leg's latest state and closes it on a terminal update or an
+// error.
+func (r *RouteReport) update(i int, x *order.Detail, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	leg := &r.legs[i]
+	if leg.closed {
+		return
+	}
+
+	if x != nil {
+		if executed := executedAmount(*x); executed > leg.Executed {
+			price := x.AverageExecutedPrice
+			if price == 0 {
+				price = x.Price
+			}
+
+			leg.AveragePrice = (leg.AveragePrice*leg.Executed + price*(executed-leg.Executed)) / executed
+			leg.Executed = executed
+		}
+
+		leg.closed = !x.IsActive()
+	}
+
+	if err != nil {
+		leg.Err = err
+		leg.closed = true
+	}
+
+	for _, leg := range r.legs {
+		if !leg.closed {
+			return
+		}
+	}
+
+	close(r.done)
+}
+
+// +------------------------+
+// | routeLeg: observations |
+// +------------------------+
+
+func (l *routeLeg) OnPartiallyFilled(k *Keep, e exchange.IBotExchange, x order.Detail, delta float64) {
+	l.report.update(l.index, &x, nil)
+}
+
+func (l *routeLeg) OnFilled(k *Keep, e exchange.IBotExchange, x order.Detail) {
+	l.report.update(l.index, &x, nil)
+}
+
+func (l *routeLeg) OnCancelled(k *Keep, e exchange.IBotExchange, x order.Detail) {
+	l.report.update(l.index, &x, nil)
+}
+
+func (l *routeLeg) OnRejected(k *Keep, e exchange.IBotExchange, x order.Detail) {
+	l.report.update(l.index, &x, nil)
+}
+
+func (l *routeLeg) OnExpired(k *Keep, e exchange.IBotExchange, x order.Detail) {
+	l.report.update(l.index, &x, nil)
+}
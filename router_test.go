@@ -0,0 +1,111 @@
+package dola_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/numeusxyz/dola"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/orderbook"
+)
+
+// nolint: exhaustivestruct
+func newRouterFixture(k *dola.Keep) (*dola.Router, *fakeExchange, *fakeExchange, dola.RouteRequest) {
+	var (
+		r    = dola.NewRouter()
+		a    = newFakeExchange("a")
+		b    = newFakeExchange("b")
+		pair = currency.NewPair(currency.BTC, currency.USDT)
+	)
+
+	a.feeRate = 0.001
+	b.feeRate = 0.005
+
+	for _, x := range []struct {
+		e    *fakeExchange
+		asks []orderbook.Item
+	}{
+		{a, []orderbook.Item{{Price: 100, Amount: 1}, {Price: 102, Amount: 5}}},
+		{b, []orderbook.Item{{Price: 100.2, Amount: 1}, {Price: 101, Amount: 1}}},
+	} {
+		k.OnOrderBook(x.e, orderbook.Base{Asks: x.asks, Pair: pair, Asset: asset.Spot})
+	}
+
+	return r, a, b, dola.RouteRequest{
+		Pair:      pair,
+		AssetType: asset.Spot,
+		Side:      order.Buy,
+		Amount:    3,
+		Exchanges: []exchange.IBotExchange{a, b},
+	}
+}
+
+func TestRouter_Plan(t *testing.T) {
+	t.Parallel()
+
+	var k dola.Keep
+
+	r, _, _, req := newRouterFixture(&k)
+
+	legs, err := r.Plan(context.Background(), &k, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(legs) != 2 ||
+		legs[0].Exchange != "a" || legs[0].Amount != 1 || legs[0].Price != 100 ||
+		legs[1].Exchange != "b" || legs[1].Amount != 2 || legs[1].Price != 101 {
+		t.Errorf("unexpected legs: %+v", legs)
+	}
+
+	req.LimitPrice = 100.1
+
+	if legs, err = r.Plan(context.Background(), &k, req); err != nil || len(legs) != 1 || legs[0].Amount != 1 {
+		t.Errorf("unexpected legs: %+v, %v", legs, err)
+	}
+
+	req.LimitPrice = 99
+
+	if _, err := r.Plan(context.Background(), &k, req); !errors.Is(err, dola.ErrNoLiquidity) {
+		t.Errorf("have %v, want %v", err, dola.ErrNoLiquidity)
+	}
+}
+
+// nolint: exhaustivestruct
+func TestRouter_Route(t *testing.T) {
+	t.Parallel()
+
+	var k dola.Keep
+
+	r, a, b, req := newRouterFixture(&k)
+
+	report, err := r.Route(context.Background(), &k, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, e := range []*fakeExchange{a, b} {
+		if xs := e.submissions(); len(xs) != 1 || !xs[0].ImmediateOrCancel {
+			t.Fatalf("unexpected submissions to %s: %+v", e.GetName(), xs)
+		}
+	}
+
+	k.OnOrder(a, order.Detail{ID: "1", Amount: 1, ExecutedAmount: 1, Price: 100, Status: order.Filled})
+	k.OnOrder(b, order.Detail{ID: "1", Amount: 2, ExecutedAmount: 2, Price: 100.6, Status: order.Filled})
+
+	select {
+	case <-report.Done():
+	case <-time.After(time.Second):
+		t.Fatal("report not done")
+	}
+
+	if report.Executed() != 3 || math.Abs(report.AveragePrice()-(100+201.2)/3) > 1e-9 {
+		t.Errorf("have %v at %v", report.Executed(), report.AveragePrice())
+	}
+}
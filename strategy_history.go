@@ -3,13 +3,20 @@ package dola
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/rs/zerolog/log"
+	"github.com/thrasher-corp/gocryptotrader/currency"
 	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
 	"github.com/thrasher-corp/gocryptotrader/exchanges/account"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
 	"github.com/thrasher-corp/gocryptotrader/exchanges/fill"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/kline"
 	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
 	"github.com/thrasher-corp/gocryptotrader/exchanges/orderbook"
 	"github.com/thrasher-corp/gocryptotrader/exchanges/stream"
@@ -32,153 +39,739 @@ type Array interface {
 
 type Historian struct {
 	// Stateless.
-	f        func(state Array)
-	interval time.Duration
+	f func(state Array)
 
 	// Stateful.
-	epoch int64
-	state Array
+	gate  epochGate
+	state CircularArray
 }
 
 func NewHistorian(interval time.Duration, stateLength int, f func(Array)) Historian {
-	state := NewCircularArray(stateLength)
-
 	return Historian{
-		f:        f,
-		interval: interval,
-		epoch:    0,
-		state:    &state,
+		f:     f,
+		gate:  epochGate{interval: interval, epoch: 0},
+		state: NewCircularArray(stateLength),
 	}
 }
 
 func (u *Historian) Push(x interface{}) {
-	u.state.(*CircularArray).Push(x)
+	u.state.Push(x)
 }
 
 func (u *Historian) Update(now time.Time, x interface{}) {
+	if !u.gate.admit(now) {
+		return
+	}
+
+	u.state.Push(x)
+
+	if u.f != nil {
+		u.f(&u.state)
+	}
+}
+
+// Floats returns the State array, but casted to []float64.
+func (u *Historian) Floats() []float64 {
+	return u.state.Floats()
+}
+
+func (u *Historian) snapshot() Array {
+	state := NewCircularArray(u.state.Cap())
+	u.state.Range(func(i int, x interface{}) bool {
+		state.Push(x)
+
+		return true
+	})
+
+	return &state
+}
+
+// +----------------+
+// | TypedHistorian |
+// +----------------+
+
+// TypedHistorian is a Historian whose state holds values of type T.  Unlike
+// Historian, it doesn't box values, so updates don't allocate.
+type TypedHistorian[T any] struct {
+	// Stateless.
+	f func(state *Ring[T])
+
+	// Stateful.
+	gate  epochGate
+	state Ring[T]
+}
+
+func NewTypedHistorian[T any](interval time.Duration, stateLength int, f func(*Ring[T])) TypedHistorian[T] {
+	return TypedHistorian[T]{
+		f:     f,
+		gate:  epochGate{interval: interval, epoch: 0},
+		state: NewRing[T](stateLength),
+	}
+}
+
+func (u *TypedHistorian[T]) Push(x T) {
+	u.state.Push(x)
+}
+
+func (u *TypedHistorian[T]) Update(now time.Time, x T) {
+	if !u.gate.admit(now) {
+		return
+	}
+
+	u.state.Push(x)
+
+	if u.f != nil {
+		u.f(&u.state)
+	}
+}
+
+func (u *TypedHistorian[T]) State() *Ring[T] {
+	return &u.state
+}
+
+func (u *TypedHistorian[T]) snapshot() Array {
+	state := NewCircularArray(u.state.Cap())
+	u.state.Range(func(i int, x T) bool {
+		state.Push(x)
+
+		return true
+	})
+
+	return &state
+}
+
+// epochGate lets through one update per interval, if there is one.
+type epochGate struct {
+	interval time.Duration
+	epoch    int64
+}
+
+func (g *epochGate) admit(now time.Time) bool {
 	// If there is an interval specified, we should update once each interval.
-	if u.interval != 0 {
-		// Compute the current epoch.
-		epoch := now.UnixNano() / u.interval.Nanoseconds()
+	if g.interval == 0 {
+		return true
+	}
 
-		// If we're in the same epoch as the last update, return.
-		if u.epoch == epoch {
-			return
+	// Compute the current epoch.
+	epoch := now.UnixNano() / g.interval.Nanoseconds()
+
+	// If we're in the same epoch as the last update, return.
+	if g.epoch == epoch {
+		return false
+	}
+
+	// // If this is the first ever update, just assign the epoch and move on.
+	// // Otherwise the first update would be imbalanced.
+	// if g.epoch == 0 {
+	// 	g.epoch = epoch
+
+	// 	return false
+	// }
+
+	// We move on with the state update.
+	g.epoch = epoch
+
+	return true
+}
+
+// +--------+
+// | Events |
+// +--------+
+
+// Event identifies the kind of event a historian records.
+type Event int
+
+const (
+	OnPriceEvent Event = iota + 1
+	OnKlineEvent
+	OnOrderBookEvent
+	OnOrderEvent
+	OnTradeEvent
+	OnFillEvent
+	OnFundingEvent
+	OnBalanceChangeEvent
+)
+
+var eventNames = map[Event]string{
+	OnPriceEvent:         "OnPrice",
+	OnKlineEvent:         "OnKline",
+	OnOrderBookEvent:     "OnOrderBook",
+	OnOrderEvent:         "OnOrder",
+	OnTradeEvent:         "OnTrade",
+	OnFillEvent:          "OnFill",
+	OnFundingEvent:       "OnFunding",
+	OnBalanceChangeEvent: "OnBalanceChange",
+}
+
+func (e Event) String() string {
+	if name, ok := eventNames[e]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("Event(%d)", int(e))
+}
+
+// ParseEvent maps the name of a Strategy method, e.g. "OnPrice", to its Event.
+func ParseEvent(name string) (Event, error) {
+	for e, x := range eventNames {
+		if x == name {
+			return e, nil
 		}
+	}
 
-		// // If this is the first ever update, just assign the epoch and move on.
-		// // Otherwise the first update would be imbalanced.
-		// if u.epoch == 0 {
-		// 	u.epoch = epoch
+	return 0, fmt.Errorf("%w: %s", ErrUnknownEvent, name)
+}
+
+// +------------+
+// | Extractors |
+// +------------+
 
-		// 	return
-		// }
+// Extractor maps an event to the value pushed to a historian.  If ok is false,
+// the event is skipped.  Trades and fills are passed one by one, i.e. as
+// trade.Data and fill.Data.
+type Extractor func(x interface{}) (value interface{}, ok bool)
 
-		// We move on with the state update.
-		u.epoch = epoch
+// Boxed turns a typed extractor, such as ExtractLast, into an Extractor.
+func Boxed[T any](f func(x interface{}) (T, bool)) Extractor {
+	return func(x interface{}) (interface{}, bool) {
+		return f(x)
 	}
+}
+
+// ExtractLast extracts the last price of a ticker.Price.
+func ExtractLast(x interface{}) (float64, bool) {
+	p, ok := x.(ticker.Price)
 
-	u.state.(*CircularArray).Push(x)
-	u.f(u.state)
+	return p.Last, ok
 }
 
-// Floats returns the State array, but casted to []float64.
-func (u *Historian) Floats() []float64 {
-	return u.state.Floats()
+// ExtractClose extracts the close price of a stream.KlineData.
+func ExtractClose(x interface{}) (float64, bool) {
+	c, ok := x.(stream.KlineData)
+
+	return c.ClosePrice, ok
+}
+
+// ExtractMid extracts the mid price of an orderbook.Base.  One-sided books are
+// skipped.
+func ExtractMid(x interface{}) (float64, bool) {
+	b, ok := x.(orderbook.Base)
+	if !ok || len(b.Bids) == 0 || len(b.Asks) == 0 {
+		return 0, false
+	}
+
+	return (b.Bids[0].Price + b.Asks[0].Price) / 2, true // nolint: gomnd
+}
+
+// ExtractPrice extracts the price of a trade.Data, fill.Data or order.Detail.
+func ExtractPrice(x interface{}) (float64, bool) {
+	switch x := x.(type) {
+	case trade.Data:
+		return x.Price, true
+	case fill.Data:
+		return x.Price, true
+	case order.Detail:
+		return x.Price, true
+	default:
+		return 0, false
+	}
+}
+
+// ExtractAmount extracts the amount of a trade.Data, fill.Data,
+// stream.FundingData or account.Change.
+func ExtractAmount(x interface{}) (float64, bool) {
+	switch x := x.(type) {
+	case trade.Data:
+		return x.Amount, true
+	case fill.Data:
+		return x.Amount, true
+	case stream.FundingData:
+		return x.Amount, true
+	case account.Change:
+		return x.Amount, true
+	default:
+		return 0, false
+	}
+}
+
+// ExtractRate extracts the rate of a stream.FundingData.
+func ExtractRate(x interface{}) (float64, bool) {
+	f, ok := x.(stream.FundingData)
+
+	return f.Rate, ok
 }
 
 // +-----------------+
 // | HistoryStrategy |
 // +-----------------+
 
-var ErrUnknownEvent = errors.New("unknown event")
+var (
+	ErrUnknownEvent      = errors.New("unknown event")
+	ErrCannotWarmUp      = errors.New("historian cannot be warmed up")
+	ErrHistorianExists   = errors.New("historian already exists")
+	ErrHistorianNotFound = errors.New("historian not found")
+)
+
+// HistorianConfig describes a historian added through AddHistorian.
+//
+// Exchange, Asset and Pair scope the events recorded.  Each of them left empty
+// is a wildcard, e.g. a historian with just Exchange set records the events of
+// all instruments on that exchange.  Events that carry no pair, such as balance
+// changes, are only recorded by historians without a Pair.
+type HistorianConfig struct {
+	// Name, if set, identifies the historian for Keep.Historian.  Names have to
+	// be unique.
+	Name     string
+	Exchange string
+	Asset    asset.Item
+	Pair     currency.Pair
+	Event    Event
+	// Interval, if non-zero, is the period values are sampled or aggregated
+	// over.
+	Interval    time.Duration
+	StateLength int
+	// Aggregation selects how the values within each Interval are recorded.
+	// Values have to be float64s or Bars, except for SampleFirst and
+	// AggregateCount; others are skipped.  Aggregates are recorded once their
+	// interval closes, see Aggregation.
+	Aggregation Aggregation
+	// FillEmpty records intervals without values: the value of the previous
+	// interval is repeated, except for AggregateSum and AggregateCount which
+	// record zero and AggregateOHLC which records a flat Bar at the previous
+	// close.  SampleFirst can't fill empty intervals.
+	FillEmpty bool
+	// Extract maps events to the values pushed.  If nil, events are pushed as
+	// they are.
+	Extract Extractor
+	// F is called with the state after each update.  Calls are serialised,
+	// but aggregates of intervals closed by the clock are recorded from a
+	// timer goroutine (see Aggregation).
+	F func(Array)
+	// WarmUp, if non-zero, is how far back history is fetched from the exchange
+	// to pre-fill the state during HistoryStrategy.Init.  Exchange, Asset and
+	// Pair have to be set.  OnPrice and OnKline historians are pre-filled from
+	// candles of Interval (a minute if zero), OnTrade historians from trades.
+	// Historians added after Init aren't warmed up.
+	WarmUp time.Duration
+}
+
+// TypedHistorianConfig describes a TypedHistorian added through
+// AddTypedHistorian.  The Extract and F of the embedded HistorianConfig are
+// ignored in favour of their typed counterparts.
+type TypedHistorianConfig[T any] struct {
+	HistorianConfig
+	// Extract maps events to the values pushed.  If nil, events of type T are
+	// pushed as they are and others are skipped.
+	Extract func(x interface{}) (T, bool)
+	// F is called with the state after each update.
+	F func(state *Ring[T])
+}
+
+// historianEntry is a historian registered with a HistoryStrategy.
+type historianEntry struct {
+	config HistorianConfig
+	// mu serializes observe and snapshot, as a historian with a wildcard
+	// exchange is updated from the threads of several exchanges, and may be
+	// queried from any thread.
+	mu       sync.Mutex
+	observe  func(now time.Time, x interface{})
+	snapshot func() Array
+	removed  bool
+	// agg, if set, aggregates the values observed.  timer closes its open
+	// interval, the one scheduled, once its time is up.
+	agg       *aggregator
+	timer     *time.Timer
+	scheduled int64
+}
+
+// matches reports whether events of the given instrument are recorded.  An
+// empty asset or pair stands for one the event doesn't carry.
+func (h *historianEntry) matches(a asset.Item, p currency.Pair) bool {
+	if h.config.Asset != "" && h.config.Asset != a {
+		return false
+	}
+
+	if !h.config.Pair.IsEmpty() && (p.IsEmpty() || !h.config.Pair.Equal(p)) {
+		return false
+	}
+
+	return true
+}
+
+func (h *historianEntry) update(now time.Time, x interface{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// The entry may have been removed after the event got dispatched to it.
+	if !h.removed {
+		h.observe(now, x)
+		h.schedule(now)
+	}
+}
+
+// schedule arms the timer closing the open interval of the aggregator, once
+// per interval.  The interval's remaining time is measured from now, the time
+// of the event that opened it.  h.mu must be held.
+func (h *historianEntry) schedule(now time.Time) {
+	a := h.agg
+	if a == nil || a.n == 0 || (h.timer != nil && h.scheduled == a.epoch) {
+		return
+	}
+
+	h.scheduled = a.epoch
+
+	if h.timer == nil {
+		h.timer = time.AfterFunc(a.end().Sub(now), h.flush)
+	} else {
+		h.timer.Reset(a.end().Sub(now))
+	}
+}
+
+// flush closes the interval scheduled, unless values of a later one closed it
+// already.
+func (h *historianEntry) flush() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.removed && h.agg.n > 0 && h.agg.epoch == h.scheduled {
+		h.agg.flush()
+	}
+}
+
+// HistorianHandle refers to a historian added to a HistoryStrategy.
+type HistorianHandle struct {
+	strategy *HistoryStrategy
+	entry    *historianEntry
+}
+
+// Remove stops the historian from recording events.  Once Remove returns, its F
+// isn't called anymore.  Removing a historian more than once is a no-op.
+//
+// Like Snapshot, Remove must not be called from within the historian's own F,
+// as it waits for F to return.  To remove a historian from its F, call Remove
+// from another goroutine: F may then be called a few more times.
+func (h *HistorianHandle) Remove() {
+	h.strategy.remove(h.entry)
+
+	h.entry.mu.Lock()
+	h.entry.removed = true
+
+	if h.entry.timer != nil {
+		h.entry.timer.Stop()
+	}
+
+	h.entry.mu.Unlock()
+}
+
+// Snapshot returns a copy of the historian's state.  It must not be called from
+// within the historian's own F.
+func (h *HistorianHandle) Snapshot() Array {
+	h.entry.mu.Lock()
+	defer h.entry.mu.Unlock()
+
+	return h.entry.snapshot()
+}
+
+// historians maps events to exchange names to the historians recording them.
+type historians map[Event]map[string][]*historianEntry
+
+// clone copies historians down to the slices, so that it can be modified while
+// the original is being read.
+func (xs historians) clone() historians {
+	ys := make(historians, len(xs))
+
+	for event, units := range xs {
+		ys[event] = make(map[string][]*historianEntry, len(units))
+
+		for key, entries := range units {
+			ys[event][key] = append([]*historianEntry{}, entries...)
+		}
+	}
+
+	return ys
+}
 
 type HistoryStrategy struct {
-	// mutex ensure write serialization of onPriceUnits/onOrderUnits
-	mu           sync.Mutex
-	onPriceUnits map[string][]*Historian
-	onOrderUnits map[string][]*Historian
+	// mutex ensure write serialization of units and names
+	mu sync.Mutex
+	// units holds historians.  It is copied on write, so that events are
+	// dispatched without locking while historians are added and removed.  It
+	// stays empty until the first historian is added, so that a
+	// HistoryStrategy can be copied until then.
+	units atomic.Value
+	names map[string]*historianEntry
 }
 
 func NewHistoryStrategy() HistoryStrategy {
 	return HistoryStrategy{
-		mu:           sync.Mutex{},
-		onPriceUnits: make(map[string][]*Historian),
-		onOrderUnits: make(map[string][]*Historian),
+		mu:    sync.Mutex{},
+		units: atomic.Value{},
+		names: make(map[string]*historianEntry),
 	}
 }
 
-func (r *HistoryStrategy) BindOnPrice(unit *Historian) {
+// BindOnPrice makes unit record the tickers of all exchanges and pairs.
+func (r *HistoryStrategy) BindOnPrice(unit *Historian) *HistorianHandle {
+	// nolint: exhaustivestruct
+	h, err := r.add(HistorianConfig{Event: OnPriceEvent}, nil, unit.Update, unit.snapshot)
+	if err != nil {
+		panic(err)
+	}
+
+	return h
 }
 
-func (r *HistoryStrategy) AddHistorian(
-	exchangeName,
-	eventName string,
-	interval time.Duration,
-	stateLength int,
-	f func(Array),
-) error {
-	key := strings.ToLower(exchangeName)
-	historian := NewHistorian(interval, stateLength, f)
+// Snapshot returns a copy of the state of the historian with the given name.
+func (r *HistoryStrategy) Snapshot(name string) (Array, error) {
+	r.mu.Lock()
+	entry, ok := r.names[name]
+	r.mu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrHistorianNotFound, name)
+	}
+
+	return (&HistorianHandle{strategy: r, entry: entry}).Snapshot(), nil
+}
+
+// load returns the historians, nil until the first one is added.
+func (r *HistoryStrategy) load() historians {
+	units, _ := r.units.Load().(historians)
+
+	return units
+}
+
+// AddHistorian adds a historian, which may be done at any time.  Use the
+// returned handle to remove it.  Aggregations other than SampleFirst and
+// AggregateCount require an Extract yielding float64s or Bars.
+func (r *HistoryStrategy) AddHistorian(c HistorianConfig) (*HistorianHandle, error) {
+	var (
+		agg      *aggregator
+		push     func(time.Time, interface{})
+		snapshot func() Array
+	)
+
+	if c.Extract == nil && c.Aggregation != SampleFirst && c.Aggregation != AggregateCount {
+		return nil, fmt.Errorf("%w: an extractor of float64s or Bars is required", ErrInvalidAggregation)
+	}
+
+	if c.Aggregation == SampleFirst {
+		historian := NewHistorian(c.Interval, c.StateLength, c.F)
+		push, snapshot = historian.Update, historian.snapshot
+	} else {
+		// The aggregator takes over the interval.
+		historian := NewHistorian(0, c.StateLength, c.F)
+		agg = newAggregator(c,
+			func(now time.Time, x float64) { historian.Update(now, x) },
+			func(now time.Time, x Bar) { historian.Update(now, x) })
+		push, snapshot = agg.add, historian.snapshot
+	}
+
+	return r.add(c, agg, func(now time.Time, x interface{}) {
+		if c.Extract != nil {
+			var ok bool
+			if x, ok = c.Extract(x); !ok {
+				return
+			}
+		}
+
+		push(now, x)
+	}, snapshot)
+}
+
+// AddTypedHistorian is like HistoryStrategy.AddHistorian, but adds a
+// TypedHistorian.  Aggregations other than SampleFirst require T to be float64,
+// or Bar for AggregateOHLC.
+func AddTypedHistorian[T any](r *HistoryStrategy, c TypedHistorianConfig[T]) (*HistorianHandle, error) {
+	extract := c.Extract
+	if extract == nil {
+		extract = func(x interface{}) (T, bool) {
+			y, ok := x.(T)
+
+			return y, ok
+		}
+	}
+
+	if c.Aggregation == SampleFirst {
+		historian := NewTypedHistorian(c.Interval, c.StateLength, c.F)
+
+		return r.add(c.HistorianConfig, nil, func(now time.Time, x interface{}) {
+			if y, ok := extract(x); ok {
+				historian.Update(now, y)
+			}
+		}, historian.snapshot)
+	}
+
+	// The aggregator takes over the interval.  T is known to be float64 or Bar
+	// below, so values are aggregated and recorded without boxing them.
+	var (
+		historian = NewTypedHistorian(0, c.StateLength, c.F)
+		agg       *aggregator
+		observe   func(time.Time, interface{})
+	)
+
+	switch h := any(&historian).(type) {
+	case *TypedHistorian[float64]:
+		if c.Aggregation == AggregateOHLC {
+			return nil, fmt.Errorf("%w: AggregateOHLC requires Bar values", ErrInvalidAggregation)
+		}
+
+		extract, _ := any(extract).(func(interface{}) (float64, bool))
+		agg = newAggregator(c.HistorianConfig, h.Update, nil)
+		observe = func(now time.Time, x interface{}) {
+			if y, ok := extract(x); ok {
+				agg.addFloat(now, y)
+			}
+		}
+	case *TypedHistorian[Bar]:
+		if c.Aggregation != AggregateOHLC {
+			return nil, fmt.Errorf("%w: Bar values require AggregateOHLC", ErrInvalidAggregation)
+		}
+
+		extract, _ := any(extract).(func(interface{}) (Bar, bool))
+		agg = newAggregator(c.HistorianConfig, nil, h.Update)
+		observe = func(now time.Time, x interface{}) {
+			if y, ok := extract(x); ok {
+				agg.addBar(now, y)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%w: %T values can't be aggregated", ErrInvalidAggregation, *new(T))
+	}
+
+	return r.add(c.HistorianConfig, agg, observe, historian.snapshot)
+}
+
+func (r *HistoryStrategy) add(
+	c HistorianConfig,
+	agg *aggregator,
+	observe func(time.Time, interface{}),
+	snapshot func() Array,
+) (*HistorianHandle, error) {
+	if _, ok := eventNames[c.Event]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEvent, c.Event)
+	}
+
+	if err := checkAggregation(c); err != nil {
+		return nil, err
+	}
+
+	if err := checkWarmUp(c); err != nil {
+		return nil, err
+	}
+
+	key := strings.ToLower(c.Exchange)
+	entry := &historianEntry{
+		config:    c,
+		mu:        sync.Mutex{},
+		observe:   observe,
+		snapshot:  snapshot,
+		removed:   false,
+		agg:       agg,
+		timer:     nil,
+		scheduled: 0,
+	}
 
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	switch eventName {
-	case "OnPrice":
-		xs := r.onPriceUnits[key]
-		r.onPriceUnits[key] = append(xs, &historian)
-	case "OnOrder":
-		xs := r.onOrderUnits[key]
-		r.onOrderUnits[key] = append(xs, &historian)
-	default:
-		return ErrUnknownEvent
+	if c.Name != "" {
+		if _, ok := r.names[c.Name]; ok {
+			return nil, fmt.Errorf("%w: %s", ErrHistorianExists, c.Name)
+		}
+
+		if r.names == nil {
+			r.names = make(map[string]*historianEntry)
+		}
+
+		r.names[c.Name] = entry
 	}
 
-	return nil
+	units := r.load().clone()
+	if _, ok := units[c.Event]; !ok {
+		units[c.Event] = make(map[string][]*historianEntry)
+	}
+
+	units[c.Event][key] = append(units[c.Event][key], entry)
+	r.units.Store(units)
+
+	return &HistorianHandle{strategy: r, entry: entry}, nil
+}
+
+func (r *HistoryStrategy) remove(entry *historianEntry) {
+	c := entry.config
+	key := strings.ToLower(c.Exchange)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.names[c.Name] == entry {
+		delete(r.names, c.Name)
+	}
+
+	units := r.load().clone()
+	entries := units[c.Event][key]
+
+	for i, x := range entries {
+		if x == entry {
+			units[c.Event][key] = append(entries[:i], entries[i+1:]...)
+			r.units.Store(units)
+
+			return
+		}
+	}
 }
 
 // +----------+
 // | Strategy |
 // +----------+
 
+// Init warms up the historians of the exchange asking for it.  Failures are
+// logged, leaving the historian empty.
 func (r *HistoryStrategy) Init(ctx context.Context, k *Keep, e exchange.IBotExchange) error {
 	key := strings.ToLower(e.GetName())
 
-	r.mu.Lock()
-	defer r.mu.Unlock()
+	var entries []*historianEntry
 
-	r.onPriceUnits[key] = make([]*Historian, 0)
-	r.onOrderUnits[key] = make([]*Historian, 0)
+	for _, units := range r.load() {
+		for _, entry := range units[key] {
+			if entry.config.WarmUp > 0 {
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	for _, entry := range entries {
+		if err := warmUp(ctx, e, entry); err != nil {
+			c := entry.config
+			What(log.Warn().Err(err).Str("exchange", e.GetName()).Str("pair", c.Pair.String()).Str("event", c.Event.String()),
+				"unable to warm up historian")
+		}
+	}
 
 	return nil
 }
 
 func (r *HistoryStrategy) OnFunding(k *Keep, e exchange.IBotExchange, x stream.FundingData) error {
-	return nil
+	return r.fire(OnFundingEvent, e, eventTime(x.Timestamp), x)
 }
 
 func (r *HistoryStrategy) OnPrice(k *Keep, e exchange.IBotExchange, x ticker.Price) error {
 	// some exchanges (eg. Kraken) don't provide the update timestamp so we fallback to `now` when
 	// unavailable
-	lastUpdated := x.LastUpdated
-	if lastUpdated.IsZero() {
-		lastUpdated = time.Now()
-	}
-
-	return fire(r.onPriceUnits, e, lastUpdated, x)
+	return r.fire(OnPriceEvent, e, eventTime(x.LastUpdated), x)
 }
 
 func (r *HistoryStrategy) OnKline(k *Keep, e exchange.IBotExchange, x stream.KlineData) error {
-	return nil
+	return r.fire(OnKlineEvent, e, eventTime(x.StartTime, x.Timestamp), x)
 }
 
 func (r *HistoryStrategy) OnOrderBook(k *Keep, e exchange.IBotExchange, x orderbook.Base) error {
-	return nil
+	return r.fire(OnOrderBookEvent, e, eventTime(x.LastUpdated), x)
 }
 
 func (r *HistoryStrategy) OnOrder(k *Keep, e exchange.IBotExchange, x order.Detail) error {
-	return fire(r.onOrderUnits, e, x.Date, x)
+	return r.fire(OnOrderEvent, e, eventTime(x.Date, x.LastUpdated), x)
 }
 
 func (r *HistoryStrategy) OnModify(k *Keep, e exchange.IBotExchange, x order.Modify) error {
@@ -186,14 +779,27 @@ func (r *HistoryStrategy) OnModify(k *Keep, e exchange.IBotExchange, x order.Mod
 }
 
 func (r *HistoryStrategy) OnBalanceChange(k *Keep, e exchange.IBotExchange, x account.Change) error {
-	return nil
+	// account.Change carries no timestamp.
+	return r.fire(OnBalanceChangeEvent, e, time.Now(), x)
 }
 
 func (r *HistoryStrategy) OnTrade(k *Keep, e exchange.IBotExchange, x []trade.Data) error {
+	for _, y := range x {
+		if err := r.fire(OnTradeEvent, e, eventTime(y.Timestamp), y); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 func (r *HistoryStrategy) OnFill(k *Keep, e exchange.IBotExchange, x []fill.Data) error {
+	for _, y := range x {
+		if err := r.fire(OnFillEvent, e, eventTime(y.Timestamp), y); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -205,16 +811,167 @@ func (r *HistoryStrategy) Deinit(k *Keep, e exchange.IBotExchange) error {
 	return nil
 }
 
-func fire(units map[string][]*Historian, e exchange.IBotExchange, now time.Time, x interface{}) error {
+func (r *HistoryStrategy) fire(event Event, e exchange.IBotExchange, now time.Time, x interface{}) error {
 	key := strings.ToLower(e.GetName())
 
-	// MT note: if historians do not get added and removed dynamically, this method is
-	// completely safe to be used in a MT environment, because:
-	//   1. reading (without concurrent writing) a map is MT-safe,
-	//   2. all On*() events for a single exchange are invoked from the same thread.
-	for _, unit := range units[key] {
-		unit.Update(now, x)
+	a, p := instrument(x)
+
+	// Historians with a wildcard exchange are stored under the empty key.
+	units := r.load()
+	scoped, wildcard := units[event][key], units[event][""]
+
+	// MT note: this method is completely safe to be used in a MT environment,
+	// even while historians get added and removed, because:
+	//   1. the units are copied on write, so a loaded map never changes,
+	//   2. each historian serializes its updates, and ignores the ones
+	//      dispatched to it before it got removed.
+	for _, units := range [][]*historianEntry{scoped, wildcard} {
+		for _, unit := range units {
+			if unit.matches(a, p) {
+				unit.update(now, x)
+			}
+		}
 	}
 
 	return nil
 }
+
+// +---------+
+// | Warm-up |
+// +---------+
+
+func checkWarmUp(c HistorianConfig) error {
+	if c.WarmUp <= 0 {
+		return nil
+	}
+
+	if c.Exchange == "" || c.Asset == "" || c.Pair.IsEmpty() {
+		return fmt.Errorf("%w: exchange, asset and pair are required", ErrCannotWarmUp)
+	}
+
+	switch c.Event { // nolint: exhaustive
+	case OnPriceEvent, OnKlineEvent, OnTradeEvent:
+		return nil
+	default:
+		return fmt.Errorf("%w: %s", ErrCannotWarmUp, c.Event)
+	}
+}
+
+// warmUp pre-fills the state of a historian with the history that precedes the
+// current interval.  Each candle is recorded at the start of its interval, so it
+// lands in the epoch of that interval.
+func warmUp(ctx context.Context, e exchange.IBotExchange, h *historianEntry) error {
+	c := h.config
+
+	interval := c.Interval
+	if interval == 0 && c.Event != OnTradeEvent {
+		interval = time.Minute
+	}
+
+	// Truncating to a zero interval is a no-op.
+	end := time.Now().Truncate(interval)
+	start := end.Add(-c.WarmUp)
+
+	if c.Event == OnTradeEvent {
+		xs, err := e.GetHistoricTrades(ctx, c.Pair, c.Asset, start, end)
+		if err != nil {
+			return err
+		}
+
+		sort.SliceStable(xs, func(i, j int) bool { return xs[i].Timestamp.Before(xs[j].Timestamp) })
+
+		for _, x := range xs {
+			if x.Timestamp.Before(end) {
+				h.update(x.Timestamp, x)
+			}
+		}
+
+		return nil
+	}
+
+	item, err := e.GetHistoricCandles(ctx, c.Pair, c.Asset, start, end, kline.Interval(interval))
+	if err != nil {
+		return err
+	}
+
+	for _, candle := range item.Candles {
+		// The candle of the current interval is still in progress.
+		if !candle.Time.Before(end) {
+			continue
+		}
+
+		h.update(candle.Time, candleEvent(e, c, interval, candle))
+	}
+
+	return nil
+}
+
+// candleEvent converts a candle to the event a historian of c expects.
+func candleEvent(e exchange.IBotExchange, c HistorianConfig, interval time.Duration, x kline.Candle) interface{} {
+	if c.Event == OnPriceEvent {
+		// nolint: exhaustivestruct
+		return ticker.Price{
+			Last:         x.Close,
+			High:         x.High,
+			Low:          x.Low,
+			Volume:       x.Volume,
+			Open:         x.Open,
+			Close:        x.Close,
+			Pair:         c.Pair,
+			ExchangeName: e.GetName(),
+			AssetType:    c.Asset,
+			LastUpdated:  x.Time,
+		}
+	}
+
+	return stream.KlineData{
+		Timestamp:  x.Time,
+		Pair:       c.Pair,
+		AssetType:  c.Asset,
+		Exchange:   e.GetName(),
+		StartTime:  x.Time,
+		CloseTime:  x.Time.Add(interval),
+		Interval:   kline.Interval(interval).Short(),
+		OpenPrice:  x.Open,
+		ClosePrice: x.Close,
+		HighPrice:  x.High,
+		LowPrice:   x.Low,
+		Volume:     x.Volume,
+	}
+}
+
+// instrument returns the asset and pair of an event, each of them empty if
+// unknown.
+func instrument(x interface{}) (asset.Item, currency.Pair) {
+	switch x := x.(type) {
+	case ticker.Price:
+		return x.AssetType, x.Pair
+	case stream.KlineData:
+		return x.AssetType, x.Pair
+	case orderbook.Base:
+		return x.Asset, x.Pair
+	case order.Detail:
+		return x.AssetType, x.Pair
+	case trade.Data:
+		return x.AssetType, x.CurrencyPair
+	case fill.Data:
+		return x.AssetType, x.CurrencyPair
+	case stream.FundingData:
+		return x.AssetType, x.CurrencyPair
+	case account.Change:
+		return x.Asset, currency.Pair{} // nolint: exhaustivestruct
+	default:
+		return "", currency.Pair{} // nolint: exhaustivestruct
+	}
+}
+
+// eventTime returns the first non-zero timestamp, or now if there is none.
+func eventTime(ts ...time.Time) time.Time {
+	for _, t := range ts {
+		if !t.IsZero() {
+			return t
+		}
+	}
+
+	return time.Now()
+}
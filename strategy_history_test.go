@@ -1,10 +1,23 @@
 package dola_test
 
 import (
+	"context"
+	"errors"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/numeusxyz/dola"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/account"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/fill"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/kline"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/orderbook"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/stream"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/ticker"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/trade"
 )
 
 func TestHistorian_State(t *testing.T) {
@@ -47,3 +60,423 @@ func TestHistorian_Floats(t *testing.T) {
 		t.Errorf(diff)
 	}
 }
+
+// nolint: exhaustivestruct, funlen
+func TestHistoryStrategy_Events(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k  dola.Keep
+		e  = newFakeExchange("fake")
+		h  = dola.NewHistoryStrategy()
+		t0 = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	)
+
+	states := make(map[dola.Event][]float64)
+	add := func(event dola.Event, extract dola.Extractor) {
+		t.Helper()
+
+		_, err := h.AddHistorian(dola.HistorianConfig{
+			Exchange:    "FAKE",
+			Event:       event,
+			StateLength: 10,
+			Extract:     extract,
+			F:           func(a dola.Array) { states[event] = a.Floats() },
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	add(dola.OnPriceEvent, dola.Boxed(dola.ExtractLast))
+	add(dola.OnKlineEvent, dola.Boxed(dola.ExtractClose))
+	add(dola.OnOrderBookEvent, dola.Boxed(dola.ExtractMid))
+	add(dola.OnTradeEvent, dola.Boxed(dola.ExtractPrice))
+	add(dola.OnFillEvent, dola.Boxed(dola.ExtractAmount))
+	add(dola.OnFundingEvent, dola.Boxed(dola.ExtractRate))
+	add(dola.OnBalanceChangeEvent, dola.Boxed(dola.ExtractAmount))
+
+	// Historians added before Init survive it.
+	if err := h.Init(context.Background(), &k, e); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = h.OnPrice(&k, e, ticker.Price{Last: 1, LastUpdated: t0})
+	_ = h.OnKline(&k, e, stream.KlineData{ClosePrice: 2, StartTime: t0})
+	_ = h.OnOrderBook(&k, e, orderbook.Base{
+		Bids: orderbook.Items{{Price: 2, Amount: 1}},
+		Asks: orderbook.Items{{Price: 4, Amount: 1}},
+	})
+	// One-sided books are skipped.
+	_ = h.OnOrderBook(&k, e, orderbook.Base{Bids: orderbook.Items{{Price: 2, Amount: 1}}})
+	_ = h.OnTrade(&k, e, []trade.Data{{Price: 4, Timestamp: t0}, {Price: 5, Timestamp: t0}})
+	_ = h.OnFill(&k, e, []fill.Data{{Amount: 6, Timestamp: t0}})
+	_ = h.OnFunding(&k, e, stream.FundingData{Rate: 7, Timestamp: t0})
+	_ = h.OnBalanceChange(&k, e, account.Change{Amount: 8})
+	// Other exchanges aren't recorded.
+	_ = h.OnPrice(&k, newFakeExchange("other"), ticker.Price{Last: 9})
+
+	want := map[dola.Event][]float64{
+		dola.OnPriceEvent:         {1},
+		dola.OnKlineEvent:         {2},
+		dola.OnOrderBookEvent:     {3},
+		dola.OnTradeEvent:         {4, 5},
+		dola.OnFillEvent:          {6},
+		dola.OnFundingEvent:       {7},
+		dola.OnBalanceChangeEvent: {8},
+	}
+	if diff := cmp.Diff(want, states); diff != "" {
+		t.Error(diff)
+	}
+}
+
+// nolint: exhaustivestruct
+func TestHistoryStrategy_UnknownEvent(t *testing.T) {
+	t.Parallel()
+
+	h := dola.NewHistoryStrategy()
+	if _, err := h.AddHistorian(dola.HistorianConfig{Exchange: "fake"}); !errors.Is(err, dola.ErrUnknownEvent) {
+		t.Errorf("have %v, want ErrUnknownEvent", err)
+	}
+
+	if e, err := dola.ParseEvent("OnFill"); err != nil || e != dola.OnFillEvent {
+		t.Errorf("have %s, %v, want OnFill", e, err)
+	}
+
+	if _, err := dola.ParseEvent("OnNothing"); !errors.Is(err, dola.ErrUnknownEvent) {
+		t.Errorf("have %v, want ErrUnknownEvent", err)
+	}
+}
+
+// nolint: exhaustivestruct
+func TestHistoryStrategy_Scope(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k   dola.Keep
+		h   = dola.NewHistoryStrategy()
+		a   = newFakeExchange("a")
+		b   = newFakeExchange("b")
+		btc = currency.NewPair(currency.BTC, currency.USDT)
+		eth = currency.NewPair(currency.ETH, currency.USDT)
+	)
+
+	states := make(map[string][]float64)
+	add := func(name string, c dola.HistorianConfig) {
+		t.Helper()
+
+		c.Event = dola.OnPriceEvent
+		c.StateLength = 10
+		c.Extract = dola.Boxed(dola.ExtractLast)
+		c.F = func(x dola.Array) { states[name] = x.Floats() }
+
+		if _, err := h.AddHistorian(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	add("a/spot/btc", dola.HistorianConfig{Exchange: "a", Asset: asset.Spot, Pair: btc})
+	add("a/*/eth", dola.HistorianConfig{Exchange: "a", Pair: eth})
+	add("*/*/btc", dola.HistorianConfig{Pair: btc})
+	add("a/*/*", dola.HistorianConfig{Exchange: "a"})
+
+	_ = h.OnPrice(&k, a, ticker.Price{Last: 1, Pair: btc, AssetType: asset.Spot})
+	_ = h.OnPrice(&k, a, ticker.Price{Last: 2, Pair: eth, AssetType: asset.Spot})
+	_ = h.OnPrice(&k, a, ticker.Price{Last: 3, Pair: btc, AssetType: asset.Futures})
+	_ = h.OnPrice(&k, b, ticker.Price{Last: 4, Pair: btc, AssetType: asset.Spot})
+
+	want := map[string][]float64{
+		"a/spot/btc": {1},
+		"a/*/eth":    {2},
+		"*/*/btc":    {1, 3, 4},
+		"a/*/*":      {1, 2, 3},
+	}
+	if diff := cmp.Diff(want, states); diff != "" {
+		t.Error(diff)
+	}
+}
+
+// nolint: exhaustivestruct
+func TestAddTypedHistorian(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k     dola.Keep
+		e     = newFakeExchange("fake")
+		h     = dola.NewHistoryStrategy()
+		state *dola.Ring[float64]
+		last  []ticker.Price
+	)
+
+	_, err := dola.AddTypedHistorian(&h, dola.TypedHistorianConfig[float64]{
+		HistorianConfig: dola.HistorianConfig{Exchange: "fake", Event: dola.OnPriceEvent, StateLength: 2},
+		Extract:         dola.ExtractLast,
+		F:               func(x *dola.Ring[float64]) { state = x },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without an extractor, events are pushed as they are.
+	_, err = dola.AddTypedHistorian(&h, dola.TypedHistorianConfig[ticker.Price]{
+		HistorianConfig: dola.HistorianConfig{Exchange: "fake", Event: dola.OnPriceEvent, StateLength: 1},
+		F:               func(x *dola.Ring[ticker.Price]) { last = x.AppendTo(last[:0]) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, x := range []float64{1, 2, 3} {
+		_ = h.OnPrice(&k, e, ticker.Price{Last: x})
+	}
+
+	if diff := cmp.Diff([]float64{2, 3}, state.AppendTo(nil)); diff != "" {
+		t.Error(diff)
+	}
+
+	if len(last) != 1 || last[0].Last != 3 {
+		t.Errorf("have %v, want the last ticker", last)
+	}
+}
+
+// nolint: paralleltest // AllocsPerRun doesn't support parallel tests.
+func TestTypedHistorian_Allocations(t *testing.T) {
+	var (
+		now = time.Now()
+		sum float64
+		u   = dola.NewTypedHistorian(0, 16, func(x *dola.Ring[float64]) { sum += x.Last() })
+	)
+
+	if allocs := testing.AllocsPerRun(100, func() { u.Update(now, 1) }); allocs != 0 {
+		t.Errorf("have %v allocations, want none", allocs)
+	}
+
+	if u.State().Len() != 16 {
+		t.Errorf("have %d, want 16", u.State().Len())
+	}
+}
+
+// nolint: exhaustivestruct, funlen
+func TestHistoryStrategy_WarmUp(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k    dola.Keep
+		e    = newFakeExchange("fake")
+		h    = dola.NewHistoryStrategy()
+		pair = currency.NewPair(currency.BTC, currency.USDT)
+		now  = time.Now().Truncate(time.Minute)
+	)
+
+	e.candles = []kline.Candle{
+		{Time: now.Add(-2 * time.Minute), Close: 1},
+		{Time: now.Add(-time.Minute), Close: 2},
+		// Not closed yet, even if the minute turns during the test.
+		{Time: now.Add(time.Minute), Close: 3},
+	}
+	e.trades = []trade.Data{
+		{Timestamp: now.Add(-time.Second), Price: 5},
+		{Timestamp: now.Add(-2 * time.Second), Price: 4},
+	}
+
+	var prices, closes, trades []float64
+
+	add := func(event dola.Event, extract dola.Extractor, state *[]float64) {
+		t.Helper()
+
+		_, err := h.AddHistorian(dola.HistorianConfig{
+			Exchange:    "fake",
+			Asset:       asset.Spot,
+			Pair:        pair,
+			Event:       event,
+			Interval:    time.Minute,
+			StateLength: 10,
+			Extract:     extract,
+			F:           func(x dola.Array) { *state = x.Floats() },
+			WarmUp:      time.Hour,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	add(dola.OnPriceEvent, dola.Boxed(dola.ExtractLast), &prices)
+	add(dola.OnKlineEvent, dola.Boxed(dola.ExtractClose), &closes)
+	add(dola.OnTradeEvent, dola.Boxed(dola.ExtractPrice), &trades)
+
+	if err := h.Init(context.Background(), &k, e); err != nil {
+		t.Fatal(err)
+	}
+
+	// Live updates carry on in the current interval.
+	_ = h.OnPrice(&k, e, ticker.Price{Last: 3, Pair: pair, AssetType: asset.Spot, LastUpdated: now})
+
+	if diff := cmp.Diff([]float64{1, 2, 3}, prices); diff != "" {
+		t.Error(diff)
+	}
+
+	if diff := cmp.Diff([]float64{1, 2}, closes); diff != "" {
+		t.Error(diff)
+	}
+
+	// Trades are sorted, and only the first one of each interval is kept.
+	if diff := cmp.Diff([]float64{4}, trades); diff != "" {
+		t.Error(diff)
+	}
+
+	// Warming up requires an instrument.
+	_, err := h.AddHistorian(dola.HistorianConfig{Exchange: "fake", Event: dola.OnPriceEvent, WarmUp: time.Hour})
+	if !errors.Is(err, dola.ErrCannotWarmUp) {
+		t.Errorf("have %v, want ErrCannotWarmUp", err)
+	}
+}
+
+// nolint: exhaustivestruct, funlen
+func TestHistorianHandle(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k = dola.Keep{Root: dola.NewRootStrategy()}
+		e = newFakeExchange("fake")
+		h = dola.NewHistoryStrategy()
+	)
+
+	k.Root.Add("history", &h)
+
+	handle, err := k.AddHistorian(dola.HistorianConfig{
+		Name:        "last",
+		Exchange:    "fake",
+		Event:       dola.OnPriceEvent,
+		StateLength: 3,
+		Extract:     dola.Boxed(dola.ExtractLast),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := k.AddHistorian(dola.HistorianConfig{Name: "last", Event: dola.OnPriceEvent}); !errors.Is(err, dola.ErrHistorianExists) {
+		t.Errorf("have %v, want ErrHistorianExists", err)
+	}
+
+	bound := dola.NewHistorian(0, 3, nil)
+	boundHandle := h.BindOnPrice(&bound)
+
+	_ = h.OnPrice(&k, e, ticker.Price{Last: 1})
+	_ = h.OnPrice(&k, e, ticker.Price{Last: 2})
+
+	state, err := k.Historian("last")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The snapshot is a copy.
+	_ = h.OnPrice(&k, e, ticker.Price{Last: 3})
+
+	if diff := cmp.Diff([]float64{1, 2}, state.Floats()); diff != "" {
+		t.Error(diff)
+	}
+
+	if x := boundHandle.Snapshot(); x.Len() != 3 || dola.Ticker(x.Last()).Last != 3 {
+		t.Errorf("have %d tickers, want the bound historian to record all of them", x.Len())
+	}
+
+	handle.Remove()
+	handle.Remove()
+
+	_ = h.OnPrice(&k, e, ticker.Price{Last: 4})
+
+	if diff := cmp.Diff([]float64{1, 2, 3}, handle.Snapshot().Floats()); diff != "" {
+		t.Error(diff)
+	}
+
+	if _, err := k.Historian("last"); !errors.Is(err, dola.ErrHistorianNotFound) {
+		t.Errorf("have %v, want ErrHistorianNotFound", err)
+	}
+}
+
+// nolint: exhaustivestruct
+func TestHistoryStrategy_Concurrency(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k  dola.Keep
+		h  = dola.NewHistoryStrategy()
+		wg sync.WaitGroup
+	)
+
+	for _, name := range []string{"a", "b", "c"} {
+		wg.Add(1)
+
+		go func(e *fakeExchange) {
+			defer wg.Done()
+
+			for i := 0; i < 100; i++ {
+				_ = h.OnPrice(&k, e, ticker.Price{Last: float64(i)})
+			}
+		}(newFakeExchange(name))
+	}
+
+	for i := 0; i < 50; i++ {
+		handle, err := h.AddHistorian(dola.HistorianConfig{
+			Event:       dola.OnPriceEvent,
+			StateLength: 10,
+			Extract:     dola.Boxed(dola.ExtractLast),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_ = handle.Snapshot()
+
+		if i%2 == 0 {
+			handle.Remove()
+		}
+	}
+
+	wg.Wait()
+}
+
+// nolint: exhaustivestruct
+func TestHistorianHandle_RemoveFromF(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k       dola.Keep
+		e       = newFakeExchange("fake")
+		h       = dola.NewHistoryStrategy()
+		handle  *dola.HistorianHandle
+		removed = make(chan struct{})
+	)
+
+	handle, err := h.AddHistorian(dola.HistorianConfig{
+		Exchange:    "fake",
+		Event:       dola.OnPriceEvent,
+		StateLength: 3,
+		Extract:     dola.Boxed(dola.ExtractLast),
+		F: func(x dola.Array) {
+			if x.Len() == 1 {
+				go func() {
+					handle.Remove()
+					close(removed)
+				}()
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = h.OnPrice(&k, e, ticker.Price{Last: 1})
+
+	select {
+	case <-removed:
+	case <-time.After(time.Second):
+		t.Fatal("Remove didn't return")
+	}
+
+	_ = h.OnPrice(&k, e, ticker.Price{Last: 2})
+
+	if diff := cmp.Diff([]float64{1}, handle.Snapshot().Floats()); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,291 @@
+package dola
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/account"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/fill"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/kline"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/orderbook"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/stream"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/ticker"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/trade"
+)
+
+// +-----------------+
+// | KlineAggregator |
+// +-----------------+
+
+// KlineAggregator is a strategy that builds candles of a fixed interval from
+// trades or, for instruments no trades are seen for, from tickers.  Closed
+// candles are delivered to all strategies in Keep.Root through OnKline.
+//
+// A candle is closed by the first event of a later interval, so that OnKline
+// is invoked from the exchange's thread like any other event.  Intervals
+// without any events produce no candle.
+type KlineAggregator struct {
+	interval time.Duration
+	backfill int
+	asset    asset.Item
+	pairs    []currency.Pair
+
+	mu      sync.Mutex
+	markets map[marketKey]*klineMarket
+}
+
+// klineMarket is the aggregation state of a single instrument.
+type klineMarket struct {
+	exchange string
+	asset    asset.Item
+	pair     currency.Pair
+	// current is the candle in progress, if any.
+	current *stream.KlineData
+	// pending holds backfilled candles yet to be emitted.
+	pending []stream.KlineData
+	// trades is set once a trade is seen, after which tickers are ignored.
+	trades bool
+}
+
+func NewKlineAggregator(interval time.Duration) *KlineAggregator {
+	return &KlineAggregator{
+		interval: interval,
+		backfill: 0,
+		asset:    asset.Spot,
+		pairs:    []currency.Pair{},
+		mu:       sync.Mutex{},
+		markets:  make(map[marketKey]*klineMarket),
+	}
+}
+
+// Backfill fetches the last n candles of pairs from the exchanges' REST APIs on
+// Init.  They are emitted ahead of the first candle built from the stream.
+func (g *KlineAggregator) Backfill(n int, a asset.Item, pairs ...currency.Pair) *KlineAggregator {
+	g.backfill = n
+	g.asset = a
+	g.pairs = pairs
+
+	return g
+}
+
+func (g *KlineAggregator) market(exchangeName string, a asset.Item, p currency.Pair) *klineMarket {
+	key := newMarketKey(exchangeName, a, p)
+
+	m, ok := g.markets[key]
+	if !ok {
+		m = &klineMarket{ // nolint: exhaustivestruct
+			exchange: exchangeName,
+			asset:    a,
+			pair:     p,
+		}
+		g.markets[key] = m
+	}
+
+	return m
+}
+
+// update adds a trade (or a ticker, unless fromTrade) to the candle in
+// progress and emits all candles closed by it.
+func (g *KlineAggregator) update(k *Keep,
+	e exchange.IBotExchange,
+	a asset.Item,
+	p currency.Pair,
+	t time.Time,
+	price, volume float64,
+	fromTrade bool) {
+	if price <= 0 {
+		return
+	}
+
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	g.mu.Lock()
+
+	m := g.market(e.GetName(), a, p)
+	if !fromTrade && m.trades {
+		g.mu.Unlock()
+
+		return
+	}
+
+	m.trades = m.trades || fromTrade
+	start := t.Truncate(g.interval)
+
+	closed := m.pending
+	m.pending = nil
+
+	switch {
+	case m.current != nil && start.After(m.current.StartTime):
+		closed = append(closed, *m.current)
+		m.current = nil
+	case m.current != nil && start.Before(m.current.StartTime):
+		// Too late for a candle that is already closed.
+		g.mu.Unlock()
+		g.emit(k, e, closed)
+
+		return
+	}
+
+	if m.current == nil {
+		m.current = &stream.KlineData{
+			Timestamp:  t,
+			Pair:       p,
+			AssetType:  a,
+			Exchange:   e.GetName(),
+			StartTime:  start,
+			CloseTime:  start.Add(g.interval),
+			Interval:   kline.Interval(g.interval).Short(),
+			OpenPrice:  price,
+			ClosePrice: price,
+			HighPrice:  price,
+			LowPrice:   price,
+			Volume:     0,
+		}
+	}
+
+	c := m.current
+	c.Timestamp = t
+	c.ClosePrice = price
+	c.Volume += volume
+
+	if price > c.HighPrice {
+		c.HighPrice = price
+	}
+
+	if price < c.LowPrice {
+		c.LowPrice = price
+	}
+
+	g.mu.Unlock()
+
+	g.emit(k, e, closed)
+}
+
+func (g *KlineAggregator) emit(k *Keep, e exchange.IBotExchange, xs []stream.KlineData) {
+	for _, x := range xs {
+		if err := k.Root.OnKline(k, e, x); err != nil {
+			What(log.Warn().Err(err).Str("exchange", e.GetName()), "OnKline failed for aggregated candle")
+		}
+	}
+}
+
+// fetch backfills candles of a single instrument.
+func (g *KlineAggregator) fetch(ctx context.Context, e exchange.IBotExchange, p currency.Pair) error {
+	now := time.Now()
+	start := now.Truncate(g.interval).Add(-time.Duration(g.backfill) * g.interval)
+
+	item, err := e.GetHistoricCandles(ctx, p, g.asset, start, now, kline.Interval(g.interval))
+	if err != nil {
+		return err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	m := g.market(e.GetName(), g.asset, p)
+
+	for _, c := range item.Candles {
+		x := stream.KlineData{
+			Timestamp:  c.Time,
+			Pair:       p,
+			AssetType:  g.asset,
+			Exchange:   e.GetName(),
+			StartTime:  c.Time,
+			CloseTime:  c.Time.Add(g.interval),
+			Interval:   kline.Interval(g.interval).Short(),
+			OpenPrice:  c.Open,
+			ClosePrice: c.Close,
+			HighPrice:  c.High,
+			LowPrice:   c.Low,
+			Volume:     c.Volume,
+		}
+
+		// The candle of the current interval is still in progress.
+		if x.CloseTime.After(now) {
+			m.current = &x
+
+			continue
+		}
+
+		m.pending = append(m.pending, x)
+	}
+
+	return nil
+}
+
+// +--------------------+
+// | Strategy interface |
+// +--------------------+
+
+func (g *KlineAggregator) Init(ctx context.Context, k *Keep, e exchange.IBotExchange) error {
+	if g.backfill <= 0 {
+		return nil
+	}
+
+	for _, p := range g.pairs {
+		if err := g.fetch(ctx, e, p); err != nil {
+			What(log.Warn().Err(err).Str("exchange", e.GetName()).Str("pair", p.String()),
+				"unable to backfill candles")
+		}
+	}
+
+	return nil
+}
+
+func (g *KlineAggregator) OnFunding(k *Keep, e exchange.IBotExchange, x stream.FundingData) error {
+	return nil
+}
+
+func (g *KlineAggregator) OnPrice(k *Keep, e exchange.IBotExchange, x ticker.Price) error {
+	g.update(k, e, x.AssetType, x.Pair, x.LastUpdated, x.Last, 0, false)
+
+	return nil
+}
+
+func (g *KlineAggregator) OnKline(k *Keep, e exchange.IBotExchange, x stream.KlineData) error {
+	return nil
+}
+
+func (g *KlineAggregator) OnOrderBook(k *Keep, e exchange.IBotExchange, x orderbook.Base) error {
+	return nil
+}
+
+func (g *KlineAggregator) OnOrder(k *Keep, e exchange.IBotExchange, x order.Detail) error {
+	return nil
+}
+
+func (g *KlineAggregator) OnModify(k *Keep, e exchange.IBotExchange, x order.Modify) error {
+	return nil
+}
+
+func (g *KlineAggregator) OnBalanceChange(k *Keep, e exchange.IBotExchange, x account.Change) error {
+	return nil
+}
+
+func (g *KlineAggregator) OnTrade(k *Keep, e exchange.IBotExchange, xs []trade.Data) error {
+	for _, x := range xs {
+		g.update(k, e, x.AssetType, x.CurrencyPair, x.Timestamp, x.Price, x.Amount, true)
+	}
+
+	return nil
+}
+
+func (g *KlineAggregator) OnFill(k *Keep, e exchange.IBotExchange, x []fill.Data) error {
+	return nil
+}
+
+func (g *KlineAggregator) OnUnrecognized(k *Keep, e exchange.IBotExchange, x interface{}) error {
+	return nil
+}
+
+func (g *KlineAggregator) Deinit(k *Keep, e exchange.IBotExchange) error {
+	return nil
+}
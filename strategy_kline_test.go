@@ -0,0 +1,143 @@
+package dola_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/numeusxyz/dola"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/kline"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/stream"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/ticker"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/trade"
+)
+
+// klineRecorder records the candles it is given.
+type klineRecorder struct {
+	dola.VerboseStrategy
+
+	xs []stream.KlineData
+}
+
+func (r *klineRecorder) OnKline(k *dola.Keep, e exchange.IBotExchange, x stream.KlineData) error {
+	r.xs = append(r.xs, x)
+
+	return nil
+}
+
+type ohlcv struct {
+	Start                  time.Time
+	Open, High, Low, Close float64
+	Volume                 float64
+}
+
+func candles(xs []stream.KlineData) []ohlcv {
+	ys := make([]ohlcv, 0, len(xs))
+	for _, x := range xs {
+		ys = append(ys, ohlcv{x.StartTime, x.OpenPrice, x.HighPrice, x.LowPrice, x.ClosePrice, x.Volume})
+	}
+
+	return ys
+}
+
+// nolint: exhaustivestruct
+func TestKlineAggregator(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k    = dola.Keep{Root: dola.NewRootStrategy()}
+		e    = newFakeExchange("fake")
+		pair = currency.NewPair(currency.BTC, currency.USDT)
+		t0   = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+		g    = dola.NewKlineAggregator(time.Minute)
+		rec  = &klineRecorder{}
+		at   = func(d time.Duration, price, amount float64) trade.Data {
+			return trade.Data{CurrencyPair: pair, AssetType: asset.Spot, Timestamp: t0.Add(d), Price: price, Amount: amount}
+		}
+	)
+
+	k.Root.Add("klines", g)
+	k.Root.Add("recorder", rec)
+
+	// Tickers are used only until the first trade.
+	for _, x := range []interface{}{
+		ticker.Price{Last: 90, Pair: pair, AssetType: asset.Spot, LastUpdated: t0.Add(-time.Minute)},
+		[]trade.Data{at(0, 100, 1), at(10*time.Second, 105, 2)},
+		ticker.Price{Last: 1, Pair: pair, AssetType: asset.Spot, LastUpdated: t0.Add(20 * time.Second)},
+		[]trade.Data{at(30*time.Second, 95, 1), at(50*time.Second, 101, 1)},
+		// Skips an interval.
+		[]trade.Data{at(3*time.Minute, 102, 1)},
+	} {
+		var err error
+
+		switch x := x.(type) {
+		case ticker.Price:
+			err = k.Root.OnPrice(&k, e, x)
+		case []trade.Data:
+			err = k.Root.OnTrade(&k, e, x)
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []ohlcv{
+		{t0.Add(-time.Minute), 90, 90, 90, 90, 0},
+		{t0, 100, 105, 95, 101, 5},
+	}
+	if diff := cmp.Diff(want, candles(rec.xs)); diff != "" {
+		t.Error(diff)
+	}
+
+	if rec.xs[1].Interval != "1m" || !rec.xs[1].CloseTime.Equal(t0.Add(time.Minute)) {
+		t.Errorf("unexpected candle: %+v", rec.xs[1])
+	}
+}
+
+// nolint: exhaustivestruct
+func TestKlineAggregator_Backfill(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k    = dola.Keep{Root: dola.NewRootStrategy()}
+		e    = newFakeExchange("fake")
+		pair = currency.NewPair(currency.BTC, currency.USDT)
+		now  = time.Now().Truncate(time.Hour)
+		g    = dola.NewKlineAggregator(time.Hour).Backfill(2, asset.Spot, pair)
+		rec  = &klineRecorder{}
+	)
+
+	e.candles = []kline.Candle{
+		{Time: now.Add(-time.Hour), Open: 1, High: 2, Low: 1, Close: 2, Volume: 10},
+		{Time: now, Open: 2, High: 3, Low: 2, Close: 3, Volume: 5},
+	}
+
+	k.Root.Add("klines", g)
+	k.Root.Add("recorder", rec)
+
+	if err := g.Init(context.Background(), &k, e); err != nil {
+		t.Fatal(err)
+	}
+
+	// The current interval continues from the backfilled candle.
+	trades := []trade.Data{
+		{CurrencyPair: pair, AssetType: asset.Spot, Timestamp: now.Add(time.Minute), Price: 4, Amount: 1},
+		{CurrencyPair: pair, AssetType: asset.Spot, Timestamp: now.Add(time.Hour), Price: 5, Amount: 1},
+	}
+	if err := g.OnTrade(&k, e, trades); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []ohlcv{
+		{now.Add(-time.Hour), 1, 2, 1, 2, 10},
+		{now, 2, 4, 2, 4, 6},
+	}
+	if diff := cmp.Diff(want, candles(rec.xs)); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -114,6 +114,18 @@ func (m *RootStrategy) OnTrade(k *Keep, e exchange.IBotExchange, x []trade.Data)
 	return m.each(func(s Strategy) error { return s.OnTrade(k, e, x) })
 }
 
+// OnConsolidatedBook forwards x to the strategies implementing
+// ConsolidatedBookObserver.
+func (m *RootStrategy) OnConsolidatedBook(k *Keep, x ConsolidatedBook) error {
+	return m.each(func(s Strategy) error {
+		if o, ok := s.(ConsolidatedBookObserver); ok {
+			return o.OnConsolidatedBook(k, x)
+		}
+
+		return nil
+	})
+}
+
 func (m *RootStrategy) OnFill(k *Keep, e exchange.IBotExchange, x []fill.Data) error {
 	return m.each(func(s Strategy) error { return s.OnFill(k, e, x) })
 }
@@ -4,10 +4,15 @@ package dola
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
 
+	"github.com/thrasher-corp/gocryptotrader/common"
+	"github.com/thrasher-corp/gocryptotrader/currency"
 	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
 	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
 	"github.com/thrasher-corp/gocryptotrader/exchanges/ticker"
+	"go.uber.org/multierr"
 )
 
 var ErrNeedBalancesStrategy = errors.New("Keep should be configured with balances support")
@@ -31,48 +36,145 @@ func Holdings(k *Keep, exchangeName string) (*ExchangeHoldings, error) {
 	return holdings, nil
 }
 
+var (
+	ErrCancelNotConfirmed = errors.New("cancel-replace: order is still live")
+	ErrNothingToReplace   = errors.New("cancel-replace: order is fully executed")
+	ErrExecutionUnknown   = errors.New("cancel-replace: unable to tell how much of the order got executed")
+)
+
 func ModifyOrder(ctx context.Context,
 	k *Keep,
 	e exchange.IBotExchange,
 	mod order.Modify) (ans order.Modify, err error) {
 	// First, try to use the native exchange functionality.
 	ans, err = k.ModifyOrder(ctx, e, mod)
-	if err == nil {
-		return ans, nil
+	if err == nil || !isUnsupported(err) {
+		return ans, err
+	}
+
+	// The exchange implementation doesn't support order modifications, so
+	// fall back to cancel + submit.  Other errors, e.g. risk check rejections,
+	// mean that the modification is refused and the order left alone.
+	return CancelReplace(ctx, k, e, mod)
+}
+
+// isUnsupported reports whether err says that an exchange wrapper doesn't
+// implement a function.
+func isUnsupported(err error) bool {
+	return errors.Is(err, common.ErrFunctionNotSupported) || errors.Is(err, common.ErrNotYetImplemented)
+}
+
+// CancelReplace cancels the order identified by mod and, once the exchange
+// confirms it's no longer live, submits a replacement for the amount that's left
+// unexecuted.  The replacement takes over the order's UserData and is linked to
+// it in the registry (see Keep.OrderLineage).  The UserData is detached from the
+// order while it's being replaced, so that its observers don't take the
+// cancellation for the end of the order.  If nothing gets submitted, it's
+// attached back and notified of the updates it missed.
+//
+// ErrCancelNotConfirmed is returned, and nothing gets submitted, if the order
+// may still be live, and ErrExecutionUnknown if it's not clear how much of it
+// got executed.
+func CancelReplace(ctx context.Context,
+	k *Keep,
+	e exchange.IBotExchange,
+	mod order.Modify) (ans order.Modify, err error) {
+	detached, loaded := k.registry.SetUserData(e.GetName(), mod.ID, nil)
+
+	defer func() {
+		if err != nil && loaded {
+			k.reattach(e, mod.ID, detached)
+		}
+	}()
+
+	cancelErr := k.CancelOrder(ctx, e, ModifyToCancel(mod))
+
+	// The cancellation may have failed because the order is gone already,
+	// which is fine, or for any other reason, which is not.  Ask the exchange
+	// either way, also to learn about fills.
+	info, err := k.GetOrderInfo(ctx, e, mod.ID, mod.Pair, mod.AssetType)
+
+	switch {
+	case err != nil && cancelErr != nil:
+		return mod, fmt.Errorf("%w: %s: %v", ErrCancelNotConfirmed, mod.ID, multierr.Combine(cancelErr, err))
+	case err != nil:
+		// Cancelled, but the exchange can't tell about the order anymore.
+		if info, err = closedOrderInfo(ctx, k, e, mod); err != nil {
+			return mod, err
+		}
+	case info.IsActive():
+		return mod, fmt.Errorf("%w: %s: %s", ErrCancelNotConfirmed, mod.ID, info.Status)
+	}
+
+	// Prepare submission.  The replacement is a new order, with a client order
+	// ID of its own.
+	submit := ModifyToSubmit(mod)
+	submit.ID = ""
+	submit.ClientOrderID = RandomOrderID("")
+
+	if submit.Amount == 0 {
+		submit.Amount = info.Amount
+	}
+
+	submit.Amount -= executedAmount(info)
+	if submit.Amount <= 0 {
+		return mod, fmt.Errorf("%w: %s", ErrNothingToReplace, mod.ID)
+	}
+
+	response, err := k.SubmitOrderUD(ctx, e, submit, detached.UserData)
+	if err != nil {
+		return mod, err
 	}
 
-	// If there is an error (even if that error says this exchange
-	// implementation does not support order modifications), we
-	// fall back to cancel + submit.
-
-	// We do not check the cancellation error on purpose as an
-	// error may be reported for a missing order, which is fine as
-	// we're submitting a new one anyways.
-	cancel := ModifyToCancel(mod)
-	_ = k.CancelOrder(ctx, e, cancel)
-
-	// Prepare submission.
-	var (
-		submit   = ModifyToSubmit(mod)
-		response order.SubmitResponse
-	)
-
-	// If there is a UserData associated with that order, resubmit
-	// the order with the same UserData.
-	value, loaded := k.GetOrderValue(e.GetName(), mod.ID)
 	if loaded {
-		response, err = k.SubmitOrderUD(ctx, e, submit, value.UserData)
-	} else {
-		// If there's not, just submit the order.
-		response, err = k.SubmitOrder(ctx, e, submit)
+		k.registry.Link(e.GetName(), mod.ID, response.OrderID)
 	}
 
+	ans = mod
 	ans.Exchange = e.GetName()
 	ans.AssetType = submit.AssetType
 	ans.Pair = submit.Pair
+	ans.Amount = submit.Amount
 	ans.ID = response.OrderID
 
-	return ans, err
+	return ans, nil
+}
+
+// closedOrderInfo returns the final state of a cancelled order the exchange
+// can't tell about anymore: from the order history or, failing that, from the
+// last update observed, if final.
+func closedOrderInfo(ctx context.Context, k *Keep, e exchange.IBotExchange, mod order.Modify) (order.Detail, error) {
+	xs, err := k.entry(e).GetOrderHistory(ctx, &order.GetOrdersRequest{
+		Type:      order.AnyType,
+		Side:      order.AnySide,
+		StartTime: time.Time{},
+		EndTime:   time.Time{},
+		OrderID:   mod.ID,
+		Pairs:     []currency.Pair{mod.Pair},
+		AssetType: mod.AssetType,
+	})
+	if err == nil {
+		for _, x := range xs {
+			if x.ID == mod.ID {
+				return x, nil
+			}
+		}
+	}
+
+	if value, ok := k.GetOrderValue(e.GetName(), mod.ID); ok && value.Detail.Status != "" && !value.IsOpen() {
+		return value.Detail, nil
+	}
+
+	return order.Detail{}, fmt.Errorf("%w: %s", ErrExecutionUnknown, mod.ID) // nolint: exhaustivestruct
+}
+
+// reattach gives an order back the UserData detached from it and notifies it of
+// the updates observed in the meantime.
+func (bot *Keep) reattach(e exchange.IBotExchange, orderID string, detached OrderValue) {
+	current, ok := bot.registry.SetUserData(e.GetName(), orderID, detached.UserData)
+	if ok && current.Detail.Status != "" {
+		notifyObservers(bot, e, detached.UserData, detached.Detail, current.Detail)
+	}
 }
 
 // Ticker casts a void* to ticker.Price.
@@ -0,0 +1,207 @@
+package dola_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/numeusxyz/dola"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/ticker"
+)
+
+// nolint: exhaustivestruct
+func TestModifyOrder_CancelReplace(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k         dola.Keep
+		e         = newFakeExchange("fake")
+		pair      = currency.NewPair(currency.BTC, currency.USDT)
+		filled    []string
+		cancelled []string
+		slots     = &dola.Slots{
+			OnFilledSlot: func(k *dola.Keep, e exchange.IBotExchange, x order.Detail) {
+				filled = append(filled, x.ID)
+			},
+			OnCancelledSlot: func(k *dola.Keep, e exchange.IBotExchange, x order.Detail) {
+				cancelled = append(cancelled, x.ID)
+			},
+		}
+	)
+
+	resp, err := k.SubmitOrderUD(context.Background(), e, order.Submit{
+		Amount:        1,
+		Price:         100,
+		Type:          order.Limit,
+		Side:          order.Buy,
+		Pair:          pair,
+		AssetType:     asset.Spot,
+		ClientOrderID: "original",
+	}, slots)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The order got partially filled before being cancelled.
+	e.active = []order.Detail{{ID: resp.OrderID, Amount: 1, ExecutedAmount: 0.25, Status: order.Cancelled}}
+	e.onCancel = func(x order.Cancel) {
+		k.OnOrder(e, e.active[0])
+	}
+
+	ans, err := dola.ModifyOrder(context.Background(), &k, e, order.Modify{
+		ID:        resp.OrderID,
+		Price:     101,
+		Amount:    1,
+		Type:      order.Limit,
+		Side:      order.Buy,
+		Pair:      pair,
+		AssetType: asset.Spot,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if xs := e.submissions(); len(xs) != 2 || xs[1].Amount != 0.75 || xs[1].Price != 101 {
+		t.Errorf("unexpected submissions: %+v", xs)
+	} else if xs[1].ID != "" || xs[1].ClientOrderID == "" || xs[1].ClientOrderID == "original" {
+		t.Errorf("replacement reuses the order's IDs: %+v", xs[1])
+	}
+
+	if lineage := k.OrderLineage(e.GetName(), ans.ID); len(lineage) != 2 || lineage[0] != resp.OrderID {
+		t.Errorf("unexpected lineage: %v", lineage)
+	}
+
+	// The UserData moved over to the replacement.
+	k.OnOrder(e, order.Detail{ID: resp.OrderID, Status: order.Filled})
+	k.OnOrder(e, order.Detail{ID: ans.ID, Status: order.Filled})
+
+	if len(filled) != 1 || filled[0] != ans.ID {
+		t.Errorf("unexpected filled notifications: %v", filled)
+	}
+
+	// The cancellation of the replaced order went unnoticed.
+	if len(cancelled) != 0 {
+		t.Errorf("unexpected cancelled notifications: %v", cancelled)
+	}
+}
+
+// nolint: exhaustivestruct
+func TestModifyOrder_ExecutionUnknown(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k         dola.Keep
+		e         = newFakeExchange("fake")
+		pair      = currency.NewPair(currency.BTC, currency.USDT)
+		cancelled []string
+		slots     = &dola.Slots{OnCancelledSlot: func(k *dola.Keep, e exchange.IBotExchange, x order.Detail) {
+			cancelled = append(cancelled, x.ID)
+		}}
+	)
+
+	resp, err := k.SubmitOrderUD(context.Background(), e, order.Submit{
+		Amount:    1,
+		Price:     100,
+		Type:      order.Limit,
+		Side:      order.Buy,
+		Pair:      pair,
+		AssetType: asset.Spot,
+	}, slots)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The cancellation goes through, but the order can't be looked up.
+	_, err = dola.CancelReplace(context.Background(), &k, e, order.Modify{
+		ID:        resp.OrderID,
+		Price:     101,
+		Amount:    1,
+		Type:      order.Limit,
+		Side:      order.Buy,
+		Pair:      pair,
+		AssetType: asset.Spot,
+	})
+	if !errors.Is(err, dola.ErrExecutionUnknown) {
+		t.Errorf("have %v, want %v", err, dola.ErrExecutionUnknown)
+	}
+
+	if n := len(e.submissions()); n != 1 {
+		t.Errorf("have %d submissions, want only the original one", n)
+	}
+
+	// The UserData is back with the order.
+	k.OnOrder(e, order.Detail{ID: resp.OrderID, Amount: 1, Status: order.Cancelled})
+
+	if len(cancelled) != 1 || cancelled[0] != resp.OrderID {
+		t.Errorf("unexpected cancelled notifications: %v", cancelled)
+	}
+}
+
+// nolint: exhaustivestruct
+func TestModifyOrder_CancelNotConfirmed(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k    dola.Keep
+		e    = newFakeExchange("fake")
+		pair = currency.NewPair(currency.BTC, currency.USDT)
+	)
+
+	e.cancelErr = errors.New("timeout")
+	e.active = []order.Detail{{ID: "1", Amount: 1, Status: order.Active}}
+
+	_, err := dola.ModifyOrder(context.Background(), &k, e, order.Modify{
+		ID:        "1",
+		Price:     101,
+		Amount:    1,
+		Type:      order.Limit,
+		Side:      order.Buy,
+		Pair:      pair,
+		AssetType: asset.Spot,
+	})
+	if !errors.Is(err, dola.ErrCancelNotConfirmed) {
+		t.Errorf("have %v, want %v", err, dola.ErrCancelNotConfirmed)
+	}
+
+	if n := len(e.submissions()); n != 0 {
+		t.Errorf("have %d submissions, want none", n)
+	}
+}
+
+// nolint: exhaustivestruct
+func TestModifyOrder_RiskRejected(t *testing.T) {
+	t.Parallel()
+
+	var (
+		k    dola.Keep
+		e    = newFakeExchange("fake")
+		pair = currency.NewPair(currency.BTC, currency.USDT)
+		risk *dola.RiskError
+	)
+
+	k.SetRiskChecks(dola.PriceBandCheck{Fraction: 0.1})
+	k.OnPrice(e, ticker.Price{Last: 100, Pair: pair, AssetType: asset.Spot})
+
+	e.active = []order.Detail{{ID: "1", Amount: 1, Status: order.Active}}
+
+	_, err := dola.ModifyOrder(context.Background(), &k, e, order.Modify{
+		ID:        "1",
+		Price:     150,
+		Amount:    1,
+		Type:      order.Limit,
+		Side:      order.Buy,
+		Pair:      pair,
+		AssetType: asset.Spot,
+	})
+	if !errors.As(err, &risk) || !errors.Is(err, dola.ErrPriceBand) {
+		t.Errorf("have %v, want %v", err, dola.ErrPriceBand)
+	}
+
+	if xs, ys := e.cancellations(), e.submissions(); len(xs) != 0 || len(ys) != 0 {
+		t.Errorf("order replaced despite rejection: %+v, %+v", xs, ys)
+	}
+}
@@ -27,17 +27,26 @@ func Stream(ctx context.Context, k *Keep, e exchange.IBotExchange, s Strategy) e
 		return err
 	}
 
+	updates := k.updates.get(e.GetName()).ready
+
 	// This goroutine never, I repeat, *never* finishes.
-	for data := range ws.ToRoutine {
-		err := handleData(k, e, s, data)
-		if err != nil {
-			What(log.Error().
-				Err(err),
-				"error handling data")
+	for {
+		select {
+		case data, ok := <-ws.ToRoutine:
+			if !ok {
+				panic("unexpected end of channel")
+			}
+
+			err := handleData(k, e, s, data)
+			if err != nil {
+				What(log.Error().
+					Err(err),
+					"error handling data")
+			}
+		case <-updates:
+			k.deliverQueuedOrders(e, s)
 		}
 	}
-
-	panic("unexpected end of channel")
 }
 
 // handleData resembles github.com/thrasher-corp/gocryptotrader.engine.websocketRoutineManager.WebsocketDataHandler.
@@ -52,10 +61,12 @@ func handleData(k *Keep, e exchange.IBotExchange, s Strategy, data interface{})
 	case stream.FundingData:
 		handleError("OnFunding", s.OnFunding(k, e, x))
 	case *ticker.Price:
+		k.OnPrice(e, *x)
 		handleError("OnPrice", s.OnPrice(k, e, *x))
 	case stream.KlineData:
 		handleError("OnKline", s.OnKline(k, e, x))
 	case *orderbook.Base:
+		k.OnOrderBook(e, *x)
 		handleError("OnOrderBook", s.OnOrderBook(k, e, *x))
 	case *order.Detail:
 		k.OnOrder(e, *x)